@@ -0,0 +1,204 @@
+// Package archive exports exercises with their tests to zip archives and
+// imports them back. Besides own format Polygon (problem.xml) and ICPC
+// (problem.yaml) problem packages are imported. Tests alone are read from
+// plain archives of input and output files.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	maxFiles     = 10000
+	maxTotalSize = 256 << 20
+
+	defaultMaxDuration = "1s"
+	defaultMaxMemory   = "256MB"
+)
+
+// Package is exercise with its tests. Exercise and tests IDs are not set.
+type Package struct {
+	Exercise *mycode.Exercise
+	Tests    []*mycode.Test
+	// LanguageSet is false when the package doesn't define exercise
+	// language, which is the case for the Polygon and ICPC packages.
+	LanguageSet bool
+}
+
+// Import reads own, Polygon or ICPC package from zip archive. Package can be
+// placed in the archive root or in the single top level directory.
+func Import(data []byte) (*Package, error) {
+
+	fs, err := unzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifest := range []string{
+		exerciseFileName, polygonFileName, icpcFileName} {
+
+		root, exists := fs.find(manifest)
+		if !exists {
+			continue
+		}
+
+		fs = fs.sub(root)
+
+		switch manifest {
+		case exerciseFileName:
+			return readOwn(fs)
+		case polygonFileName:
+			return readPolygon(fs)
+		default:
+			return readICPC(fs)
+		}
+	}
+
+	return nil, fmt.Errorf("neither %s, %s nor %s found", exerciseFileName,
+		polygonFileName, icpcFileName)
+}
+
+// files are archive files contents by slash separated paths.
+type files map[string][]byte
+
+func unzip(data []byte) (files, error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+
+	if len(zr.File) > maxFiles {
+		return nil, fmt.Errorf("too many files")
+	}
+
+	fs := files{}
+
+	var total int64
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		// Files are never written to disk, but paths escaping the archive
+		// root could be reached by includes resolved relative to them.
+		name := path.Clean(strings.TrimPrefix(f.Name, "/"))
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid file name: %s", f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+
+		// Uncompressed size from the header can't be trusted.
+		content, err := ioutil.ReadAll(
+			io.LimitReader(rc, maxTotalSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Name, err)
+		}
+
+		total += int64(len(content))
+
+		if total > maxTotalSize {
+			return nil, fmt.Errorf("archive is too large")
+		}
+
+		fs[name] = content
+	}
+
+	return fs, nil
+}
+
+// find returns directory of the file with the given name, which is the
+// closest one to the archive root.
+func (fs files) find(name string) (string, bool) {
+	var (
+		dir   string
+		found bool
+	)
+	for p := range fs {
+		if path.Base(p) != name {
+			continue
+		}
+		d := path.Dir(p)
+		if !found || strings.Count(d, "/") < strings.Count(dir, "/") ||
+			d == "." {
+			dir, found = d, true
+		}
+	}
+	return dir, found
+}
+
+func (fs files) sub(dir string) files {
+	if dir == "." {
+		return fs
+	}
+	sfs := files{}
+	for p, c := range fs {
+		if strings.HasPrefix(p, dir+"/") {
+			sfs[strings.TrimPrefix(p, dir+"/")] = c
+		}
+	}
+	return sfs
+}
+
+// glob returns sorted paths matching the pattern.
+func (fs files) glob(pattern string) []string {
+	var ps []string
+	for p := range fs {
+		if ok, _ := path.Match(pattern, p); ok {
+			ps = append(ps, p)
+		}
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+// copyTest returns copy of the test template fields, which are shared by all
+// package tests.
+func copyTest(t *mycode.Test) *mycode.Test {
+	return &mycode.Test{
+		Type:            t.Type,
+		MaxDuration:     t.MaxDuration,
+		MaxMemory:       t.MaxMemory,
+		ComparisonMode:  t.ComparisonMode,
+		FloatAbsEpsilon: t.FloatAbsEpsilon,
+		FloatRelEpsilon: t.FloatRelEpsilon,
+		CheckerLanguage: t.CheckerLanguage,
+		CheckerSource:   t.CheckerSource,
+		CheckerProtocol: t.CheckerProtocol,
+	}
+}
+
+var extLanguages = map[string]mycode.Language{
+	".c":    mycode.Language_c,
+	".cpp":  mycode.Language_cpp,
+	".cc":   mycode.Language_cpp,
+	".cxx":  mycode.Language_cpp,
+	".go":   mycode.Language_go,
+	".java": mycode.Language_java,
+	".pas":  mycode.Language_pascal,
+	".dpr":  mycode.Language_pascal,
+	".py":   mycode.Language_python,
+}
+
+func sourceLanguage(p string) (mycode.Language, error) {
+	l, exists := extLanguages[strings.ToLower(path.Ext(p))]
+	if !exists {
+		return 0, fmt.Errorf("unsupported source language: %s", p)
+	}
+	return l, nil
+}
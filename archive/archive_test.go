@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+// zipFiles returns zip archive of the files by their paths.
+func zipFiles(t *testing.T, fs map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for name, content := range fs {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		_, err = w.Write([]byte(content))
+		if err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestExportImport(t *testing.T) {
+
+	p := &Package{
+		Exercise: &mycode.Exercise{
+			Title:           "Sum",
+			Description:     "Sum two numbers.",
+			Language:        mycode.Language_cpp,
+			Estimator:       mycode.ExerciseEstimator_exponential,
+			ReferenceSource: "int main() {}",
+		},
+		Tests: []*mycode.Test{{
+			Name:            "float",
+			Type:            mycode.TestType_simple,
+			MaxDuration:     "1s",
+			MaxMemory:       "64MB",
+			Stdin:           "1.5 2\n",
+			ExpectedStdout:  "3.5\n",
+			ComparisonMode:  mycode.ComparisonMode_float_tokens,
+			FloatAbsEpsilon: 1e-6,
+			FloatRelEpsilon: 1e-9,
+		}, {
+			Name:           "empty answer",
+			Type:           mycode.TestType_simple,
+			MaxDuration:    "2s",
+			MaxMemory:      "256MB",
+			Stdin:          "",
+			ExpectedStdout: "",
+		}, {
+			Name:            "checker",
+			Type:            mycode.TestType_checker,
+			MaxDuration:     "500ms",
+			MaxMemory:       "128MB",
+			Stdin:           "1 2\n",
+			ExpectedStdout:  "3\n",
+			CheckerLanguage: mycode.Language_cpp.String(),
+			CheckerSource:   "int main() { return 0; }",
+			CheckerProtocol: mycode.CheckerProtocol_testlib_checker,
+		}},
+		LanguageSet: true,
+	}
+
+	data, err := Export(p)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	got, err := Import(data)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Exercise, p.Exercise) {
+		t.Errorf("exercise = %+v, want %+v", got.Exercise, p.Exercise)
+	}
+
+	if !got.LanguageSet {
+		t.Error("language not set")
+	}
+
+	if len(got.Tests) != len(p.Tests) {
+		t.Fatalf("got %d tests, want %d", len(got.Tests), len(p.Tests))
+	}
+
+	for i := range p.Tests {
+		if !reflect.DeepEqual(got.Tests[i], p.Tests[i]) {
+			t.Errorf("test %d = %+v, want %+v", i+1, got.Tests[i],
+				p.Tests[i])
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+
+	exercise := `{
+		"version": 1,
+		"title": "Sum",
+		"description": "Sum two numbers.",
+		"language": "go",
+		"estimator": "linear",
+		"tests": [{
+			"name": "1",
+			"type": "simple",
+			"max_duration": "1s",
+			"max_memory": "64MB"
+		}]
+	}`
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantTitle string
+		wantErr   string
+	}{{
+		name: "root",
+		files: map[string]string{
+			"exercise.json": exercise,
+			"tests/01":      "1 2\n",
+			"tests/01.a":    "3\n",
+		},
+		wantTitle: "Sum",
+	}, {
+		name: "top level directory",
+		files: map[string]string{
+			"sum/exercise.json": exercise,
+			"sum/tests/01":      "1 2\n",
+		},
+		wantTitle: "Sum",
+	}, {
+		name: "leading slash",
+		files: map[string]string{
+			"/exercise.json": exercise,
+			"/tests/01":      "1 2\n",
+		},
+		wantTitle: "Sum",
+	}, {
+		name: "parent directory",
+		files: map[string]string{
+			"../exercise.json": exercise,
+			"tests/01":         "1 2\n",
+		},
+		wantErr: "invalid file name",
+	}, {
+		name: "nested parent directory",
+		files: map[string]string{
+			"exercise.json":         exercise,
+			"tests/01":              "1 2\n",
+			"tests/../../../evil.h": "",
+		},
+		wantErr: "invalid file name",
+	}, {
+		name: "missing input",
+		files: map[string]string{
+			"exercise.json": exercise,
+		},
+		wantErr: "test 1 input not found",
+	}, {
+		name: "unsupported version",
+		files: map[string]string{
+			"exercise.json": `{"version": 2}`,
+		},
+		wantErr: "unsupported version",
+	}, {
+		name: "unknown language",
+		files: map[string]string{
+			"exercise.json": strings.Replace(exercise, `"go"`,
+				`"cobol"`, 1),
+			"tests/01": "1 2\n",
+		},
+		wantErr: "exercise language",
+	}, {
+		name: "no manifest",
+		files: map[string]string{
+			"tests/01": "1 2\n",
+		},
+		wantErr: "neither exercise.json, problem.xml nor problem.yaml found",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p, err := Import(zipFiles(t, tt.files))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err,
+						tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+
+			if p.Exercise.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", p.Exercise.Title,
+					tt.wantTitle)
+			}
+
+			if len(p.Tests) != 1 || p.Tests[0].Stdin != "1 2\n" {
+				t.Errorf("tests = %+v, want one with stdin", p.Tests)
+			}
+		})
+	}
+}
+
+func TestImportNotZip(t *testing.T) {
+	_, err := Import([]byte("not a zip"))
+	if err == nil {
+		t.Fatal("no error for not a zip")
+	}
+}
@@ -0,0 +1,250 @@
+package archive
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"gopkg.in/yaml.v2"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	icpcFileName = "problem.yaml"
+
+	icpcCheckerFileName    = "checker.cpp"
+	icpcInteractorFileName = "interactor.cpp"
+)
+
+type icpcProblem struct {
+	// Name is either string or map from language code to string.
+	Name   interface{} `yaml:"name"`
+	Limits struct {
+		Time      float64 `yaml:"time"`
+		TimeLimit float64 `yaml:"time_limit"`
+		// Memory is in megabytes.
+		Memory uint64 `yaml:"memory"`
+	} `yaml:"limits"`
+	Validation     string `yaml:"validation"`
+	ValidatorFlags string `yaml:"validator_flags"`
+}
+
+// icpcLanguages are statement languages in preference order.
+var icpcLanguages = []string{"ru", "en"}
+
+func readICPC(fs files) (*Package, error) {
+
+	var ip icpcProblem
+
+	err := yaml.Unmarshal(fs[icpcFileName], &ip)
+	if err != nil {
+		return nil, fmt.Errorf("YAML unmarshal %s: %w", icpcFileName, err)
+	}
+
+	p := &Package{
+		Exercise: &mycode.Exercise{
+			Title: icpcName(ip.Name),
+		},
+	}
+
+	p.Exercise.Description = icpcDescription(fs)
+	if p.Exercise.Description == "" {
+		p.Exercise.Description = p.Exercise.Title
+	}
+
+	maxDuration := defaultMaxDuration
+
+	timeLimit := ip.Limits.TimeLimit
+	if timeLimit == 0 {
+		timeLimit = ip.Limits.Time
+	}
+
+	if timeLimit > 0 {
+		maxDuration = time.Duration(timeLimit * float64(time.Second)).String()
+	}
+
+	maxMemory := defaultMaxMemory
+	if ip.Limits.Memory > 0 {
+		maxMemory = (datasize.ByteSize(ip.Limits.Memory) * datasize.MB).String()
+	}
+
+	test := &mycode.Test{
+		MaxDuration: maxDuration,
+		MaxMemory:   maxMemory,
+	}
+
+	validation := strings.Fields(ip.Validation)
+
+	switch {
+	case len(validation) != 0 && validation[0] == "custom":
+		test.Type = mycode.TestType_checker
+		for _, v := range validation[1:] {
+			if v == "interactive" {
+				test.Type = mycode.TestType_interactive
+			}
+		}
+		err = icpcValidatorToTest(fs, test)
+		if err != nil {
+			return nil, fmt.Errorf("output validator: %w", err)
+		}
+	default:
+		err = icpcValidatorFlagsToTest(ip.ValidatorFlags, test)
+		if err != nil {
+			return nil, fmt.Errorf("validator flags: %w", err)
+		}
+	}
+
+	inputs := append(fs.glob("data/sample/*.in"), fs.glob("data/secret/*.in")...)
+
+	for _, inputPath := range inputs {
+		t := copyTest(test)
+		t.Name = strings.TrimSuffix(strings.TrimPrefix(inputPath, "data/"), ".in")
+		t.Stdin = string(fs[inputPath])
+		t.ExpectedStdout = string(fs[strings.TrimSuffix(inputPath, ".in")+".ans"])
+		p.Tests = append(p.Tests, t)
+	}
+
+	// Packages exported from Polygon in ICPC format keep tests in Polygon
+	// layout.
+	if len(inputs) == 0 {
+		for i := 1; ; i++ {
+			stdin, exists := fs[fmt.Sprintf(inputPathFormat, i)]
+			if !exists {
+				break
+			}
+			t := copyTest(test)
+			t.Name = path.Base(fmt.Sprintf(inputPathFormat, i))
+			t.Stdin = string(stdin)
+			t.ExpectedStdout = string(fs[fmt.Sprintf(answerPathFormat, i)])
+			p.Tests = append(p.Tests, t)
+		}
+	}
+
+	if len(p.Tests) == 0 {
+		return nil, fmt.Errorf("no tests found")
+	}
+
+	return p, nil
+}
+
+func icpcName(name interface{}) string {
+	switch n := name.(type) {
+	case string:
+		return n
+	case map[interface{}]interface{}:
+		for _, l := range icpcLanguages {
+			if s, ok := n[l].(string); ok {
+				return s
+			}
+		}
+		for _, v := range n {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func icpcDescription(fs files) string {
+
+	var patterns []string
+
+	for _, l := range icpcLanguages {
+		patterns = append(patterns,
+			"problem_statement/problem."+l+".tex",
+			"statement/problem."+l+".tex",
+			"problem_statement/problem."+l+".md",
+			"statement/problem."+l+".md")
+	}
+
+	patterns = append(patterns,
+		"problem_statement/problem.tex",
+		"problem_statement/problem.*.tex",
+		"statement/problem.*.tex",
+		"statement/problem.*.md")
+
+	for _, p := range patterns {
+		if ps := fs.glob(p); len(ps) != 0 {
+			return string(fs[ps[0]])
+		}
+	}
+
+	return ""
+}
+
+// icpcValidatorToTest sets test checker or interactor from checker.cpp or
+// interactor.cpp in the package root with its includes like testlib.h
+// inlined. ICPC output validators can't be used, since they don't follow
+// testlib protocol.
+func icpcValidatorToTest(fs files, t *mycode.Test) error {
+
+	name := icpcCheckerFileName
+	if t.Type == mycode.TestType_interactive {
+		name = icpcInteractorFileName
+	}
+
+	if _, exists := fs[name]; !exists {
+		return fmt.Errorf("%s not found, ICPC output validators are "+
+			"not supported", name)
+	}
+
+	source, err := inlineIncludes(fs, name)
+	if err != nil {
+		return err
+	}
+
+	t.CheckerLanguage = mycode.Language_cpp.String()
+	t.CheckerSource = source
+	t.CheckerProtocol = mycode.CheckerProtocol_testlib_checker
+
+	return nil
+}
+
+// icpcValidatorFlagsToTest sets test comparison mode matching the ICPC
+// default output validator with the given flags. By default it is case
+// and whitespace insensitive.
+func icpcValidatorFlagsToTest(flags string, t *mycode.Test) error {
+
+	t.ComparisonMode = mycode.ComparisonMode_case_insensitive_tokens
+
+	fs := strings.Fields(flags)
+
+	for i := 0; i < len(fs); i++ {
+		switch fs[i] {
+		case "case_sensitive":
+			if t.ComparisonMode == mycode.ComparisonMode_case_insensitive_tokens {
+				t.ComparisonMode = mycode.ComparisonMode_tokens
+			}
+		case "space_change_sensitive":
+			// Token comparison modes ignore whitespace changes anyway.
+		case "float_tolerance", "float_absolute_tolerance",
+			"float_relative_tolerance":
+			if i+1 == len(fs) {
+				return fmt.Errorf("%s value missing", fs[i])
+			}
+			v, err := strconv.ParseFloat(fs[i+1], 64)
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", fs[i], err)
+			}
+			t.ComparisonMode = mycode.ComparisonMode_float_tokens
+			switch fs[i] {
+			case "float_tolerance":
+				t.FloatAbsEpsilon, t.FloatRelEpsilon = v, v
+			case "float_absolute_tolerance":
+				t.FloatAbsEpsilon = v
+			default:
+				t.FloatRelEpsilon = v
+			}
+			i++
+		default:
+			return fmt.Errorf("unsupported flag: %s", fs[i])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestImportICPC(t *testing.T) {
+
+	fs := map[string]string{
+		"sum/problem.yaml": `name:
+  en: Sum
+  ru: Сумма
+limits:
+  time_limit: 1.5
+  memory: 512
+validation: custom
+`,
+		"sum/problem_statement/problem.en.tex": "English statement",
+		"sum/problem_statement/problem.ru.tex": "Условие",
+		"sum/checker.cpp":                      "#include \"testlib.h\"\n#include \"testlib.h\"\nint main() {}\n",
+		"sum/testlib.h":                        "// testlib",
+		"sum/data/sample/1.in":                 "1 2\n",
+		"sum/data/sample/1.ans":                "3\n",
+		"sum/data/secret/2.in":                 "2 2\n",
+		"sum/data/secret/2.ans":                "4\n",
+		"sum/data/secret/2.out":                "ignored",
+	}
+
+	p, err := Import(zipFiles(t, fs))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	wantExercise := &mycode.Exercise{
+		Title:       "Сумма",
+		Description: "Условие",
+	}
+
+	if !reflect.DeepEqual(p.Exercise, wantExercise) {
+		t.Errorf("exercise = %+v, want %+v", p.Exercise, wantExercise)
+	}
+
+	checkerSource := "#line 1 \"testlib.h\"\n// testlib\n" +
+		"#line 2 \"checker.cpp\"\n\nint main() {}\n"
+
+	wantTests := []*mycode.Test{{
+		Name:            "sample/1",
+		Type:            mycode.TestType_checker,
+		MaxDuration:     "1.5s",
+		MaxMemory:       "512MB",
+		Stdin:           "1 2\n",
+		ExpectedStdout:  "3\n",
+		CheckerLanguage: mycode.Language_cpp.String(),
+		CheckerSource:   checkerSource,
+		CheckerProtocol: mycode.CheckerProtocol_testlib_checker,
+	}, {
+		Name:            "secret/2",
+		Type:            mycode.TestType_checker,
+		MaxDuration:     "1.5s",
+		MaxMemory:       "512MB",
+		Stdin:           "2 2\n",
+		ExpectedStdout:  "4\n",
+		CheckerLanguage: mycode.Language_cpp.String(),
+		CheckerSource:   checkerSource,
+		CheckerProtocol: mycode.CheckerProtocol_testlib_checker,
+	}}
+
+	if !reflect.DeepEqual(p.Tests, wantTests) {
+		t.Errorf("tests = %+v, want %+v", p.Tests, wantTests)
+	}
+}
+
+func TestImportICPCValidation(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		problem        string
+		files          map[string]string
+		wantType       mycode.TestType
+		wantMode       mycode.ComparisonMode
+		wantAbsEpsilon float64
+		wantRelEpsilon float64
+		wantErr        string
+	}{{
+		name:     "default validator",
+		problem:  "name: A\n",
+		wantType: mycode.TestType_simple,
+		wantMode: mycode.ComparisonMode_case_insensitive_tokens,
+	}, {
+		name:     "case sensitive",
+		problem:  "name: A\nvalidator_flags: case_sensitive space_change_sensitive\n",
+		wantType: mycode.TestType_simple,
+		wantMode: mycode.ComparisonMode_tokens,
+	}, {
+		name:           "float tolerance",
+		problem:        "name: A\nvalidator_flags: float_tolerance 1e-6\n",
+		wantType:       mycode.TestType_simple,
+		wantMode:       mycode.ComparisonMode_float_tokens,
+		wantAbsEpsilon: 1e-6,
+		wantRelEpsilon: 1e-6,
+	}, {
+		name:           "float relative tolerance",
+		problem:        "name: A\nvalidator_flags: float_relative_tolerance 0.01\n",
+		wantType:       mycode.TestType_simple,
+		wantMode:       mycode.ComparisonMode_float_tokens,
+		wantRelEpsilon: 0.01,
+	}, {
+		name:    "float tolerance without value",
+		problem: "name: A\nvalidator_flags: float_tolerance\n",
+		wantErr: "float_tolerance value missing",
+	}, {
+		name:    "unsupported flag",
+		problem: "name: A\nvalidator_flags: magic\n",
+		wantErr: "unsupported flag: magic",
+	}, {
+		name:    "interactive",
+		problem: "name: A\nvalidation: custom interactive\n",
+		files: map[string]string{
+			"interactor.cpp": "int main() {}\n",
+		},
+		wantType: mycode.TestType_interactive,
+	}, {
+		name:    "output validator",
+		problem: "name: A\nvalidation: custom\n",
+		files: map[string]string{
+			"output_validators/v/validate.cpp": "int main() {}\n",
+		},
+		wantErr: "checker.cpp not found",
+	}, {
+		name:    "missing include",
+		problem: "name: A\nvalidation: custom\n",
+		files: map[string]string{
+			"checker.cpp": "#include \"lib/testlib.h\"\n",
+		},
+		wantErr: "checker.cpp: included lib/testlib.h not found",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			fs := map[string]string{
+				"problem.yaml":      tt.problem,
+				"data/secret/1.in":  "1\n",
+				"data/secret/1.ans": "1\n",
+			}
+
+			for name, content := range tt.files {
+				fs[name] = content
+			}
+
+			p, err := Import(zipFiles(t, fs))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err,
+						tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+
+			test := p.Tests[0]
+
+			if test.Type != tt.wantType || test.ComparisonMode != tt.wantMode ||
+				test.FloatAbsEpsilon != tt.wantAbsEpsilon ||
+				test.FloatRelEpsilon != tt.wantRelEpsilon {
+
+				t.Errorf("test = %+v, want type %v, mode %v and epsilons "+
+					"%v, %v", test, tt.wantType, tt.wantMode,
+					tt.wantAbsEpsilon, tt.wantRelEpsilon)
+			}
+		})
+	}
+}
+
+func TestImportICPCPolygonLayout(t *testing.T) {
+
+	fs := map[string]string{
+		"problem.yaml": "name: A\nlimits:\n  time: 2\n",
+		"tests/01":     "1\n",
+		"tests/01.a":   "2\n",
+		"tests/02":     "3\n",
+	}
+
+	p, err := Import(zipFiles(t, fs))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if len(p.Tests) != 2 {
+		t.Fatalf("got %d tests, want 2", len(p.Tests))
+	}
+
+	if p.Tests[0].Name != "01" || p.Tests[0].ExpectedStdout != "2\n" ||
+		p.Tests[1].Stdin != "3\n" || p.Tests[1].MaxDuration != "2s" {
+
+		t.Errorf("tests = %+v", p.Tests)
+	}
+
+	_, err = Import(zipFiles(t, map[string]string{
+		"problem.yaml": "name: A\n",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "no tests found") {
+		t.Errorf("error = %v, want no tests found", err)
+	}
+}
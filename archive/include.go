@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// localIncludeRe matches C and C++ local include directive like
+// #include "testlib.h".
+var localIncludeRe = regexp.MustCompile(`^\s*#\s*include\s*"([^"]+)"`)
+
+// inlineIncludes returns C or C++ source with its local includes replaced
+// by the package files they refer to, since runner compiles single source
+// file and images have no testlib. Includes are resolved relative to the
+// including file. Every file is inlined once, as if it had include guard.
+// Error is returned if included file isn't found in the package.
+func inlineIncludes(fs files, sourcePath string) (string, error) {
+
+	var b strings.Builder
+
+	err := inlineFile(fs, sourcePath, map[string]bool{}, &b)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func inlineFile(fs files, p string, inlined map[string]bool,
+	b *strings.Builder) error {
+
+	inlined[p] = true
+
+	for i, line := range strings.SplitAfter(string(fs[p]), "\n") {
+		m := localIncludeRe.FindStringSubmatch(line)
+		if m == nil {
+			b.WriteString(line)
+			continue
+		}
+
+		includePath := path.Join(path.Dir(p), m[1])
+
+		if _, exists := fs[includePath]; !exists {
+			return fmt.Errorf("%s: included %s not found in package",
+				p, m[1])
+		}
+
+		// Line is kept, so compiler messages point to the right lines.
+		if inlined[includePath] {
+			b.WriteString("\n")
+			continue
+		}
+
+		fmt.Fprintf(b, "#line 1 %q\n", path.Base(includePath))
+
+		err := inlineFile(fs, includePath, inlined, b)
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(b, "#line %d %q\n", i+2, path.Base(p))
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineIncludes(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		source  string
+		want    string
+		wantErr string
+	}{{
+		name: "no includes",
+		files: map[string]string{
+			"check.cpp": "#include <cstdio>\nint main() {}",
+		},
+		source: "check.cpp",
+		want:   "#include <cstdio>\nint main() {}",
+	}, {
+		name: "relative to including file",
+		files: map[string]string{
+			"files/check.cpp":    "# include \"lib/a.h\"\nint main() {}\n",
+			"files/lib/a.h":      "#include \"b.h\"\nint a;",
+			"files/lib/b.h":      "int b;\n",
+			"files/lib/unused.h": "int unused;\n",
+		},
+		source: "files/check.cpp",
+		want: "#line 1 \"a.h\"\n" +
+			"#line 1 \"b.h\"\nint b;\n#line 2 \"a.h\"\nint a;\n" +
+			"#line 2 \"check.cpp\"\nint main() {}\n",
+	}, {
+		name: "inlined once",
+		files: map[string]string{
+			"check.cpp": "#include \"a.h\"\n#include \"b.h\"\n" +
+				"#include \"a.h\"\nint main() {}\n",
+			"a.h": "int a;\n",
+			"b.h": "#include \"a.h\"\nint b;\n",
+		},
+		source: "check.cpp",
+		want: "#line 1 \"a.h\"\nint a;\n#line 2 \"check.cpp\"\n" +
+			"#line 1 \"b.h\"\n\nint b;\n#line 3 \"check.cpp\"\n" +
+			"\nint main() {}\n",
+	}, {
+		name: "recursive include",
+		files: map[string]string{
+			"a.h": "#include \"a.h\"\nint a;\n",
+		},
+		source: "a.h",
+		want:   "\nint a;\n",
+	}, {
+		name: "missing include",
+		files: map[string]string{
+			"check.cpp": "#include \"testlib.h\"\n",
+		},
+		source:  "check.cpp",
+		wantErr: "check.cpp: included testlib.h not found in package",
+	}, {
+		name: "missing nested include",
+		files: map[string]string{
+			"check.cpp": "#include \"a.h\"\n",
+			"a.h":       "#include \"../b.h\"\n",
+		},
+		source:  "check.cpp",
+		wantErr: "a.h: included ../b.h not found in package",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			fs := files{}
+			for name, content := range tt.files {
+				fs[name] = []byte(content)
+			}
+
+			got, err := inlineIncludes(fs, tt.source)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err,
+						tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("inline includes: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("source = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	exerciseFileName = "exercise.json"
+
+	ownVersion = 1
+
+	// Tests stdins and expected stdouts are stored Polygon way, so they are
+	// readable by the Polygon tools.
+	inputPathFormat  = "tests/%02d"
+	answerPathFormat = "tests/%02d.a"
+)
+
+type ownExercise struct {
+	Version         int       `json:"version"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Language        string    `json:"language"`
+	Estimator       string    `json:"estimator"`
+	ReferenceSource string    `json:"reference_source,omitempty"`
+	Tests           []ownTest `json:"tests"`
+}
+
+type ownTest struct {
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	MaxDuration     string  `json:"max_duration"`
+	MaxMemory       string  `json:"max_memory"`
+	ComparisonMode  string  `json:"comparison_mode,omitempty"`
+	FloatAbsEpsilon float64 `json:"float_abs_epsilon,omitempty"`
+	FloatRelEpsilon float64 `json:"float_rel_epsilon,omitempty"`
+	CheckerLanguage string  `json:"checker_language,omitempty"`
+	CheckerSource   string  `json:"checker_source,omitempty"`
+	CheckerProtocol string  `json:"checker_protocol,omitempty"`
+}
+
+// Export writes exercise with its tests to zip archive in own format.
+func Export(p *Package) ([]byte, error) {
+
+	e := ownExercise{
+		Version:         ownVersion,
+		Title:           p.Exercise.Title,
+		Description:     p.Exercise.Description,
+		Language:        p.Exercise.Language.String(),
+		Estimator:       p.Exercise.Estimator.String(),
+		ReferenceSource: p.Exercise.ReferenceSource,
+	}
+
+	for _, t := range p.Tests {
+		ot := ownTest{
+			Name:        t.Name,
+			Type:        t.Type.String(),
+			MaxDuration: t.MaxDuration,
+			MaxMemory:   t.MaxMemory,
+		}
+		switch t.Type {
+		case mycode.TestType_simple:
+			ot.ComparisonMode = t.ComparisonMode.String()
+			ot.FloatAbsEpsilon = t.FloatAbsEpsilon
+			ot.FloatRelEpsilon = t.FloatRelEpsilon
+		default:
+			ot.CheckerLanguage = t.CheckerLanguage
+			ot.CheckerSource = t.CheckerSource
+			ot.CheckerProtocol = t.CheckerProtocol.String()
+		}
+		e.Tests = append(e.Tests, ot)
+	}
+
+	eJSON, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshal exercise: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	write := func(name string, content []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+		_, err = w.Write(content)
+		if err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		return nil
+	}
+
+	err = write(exerciseFileName, eJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range p.Tests {
+		err = write(fmt.Sprintf(inputPathFormat, i+1), []byte(t.Stdin))
+		if err != nil {
+			return nil, err
+		}
+		err = write(fmt.Sprintf(answerPathFormat, i+1),
+			[]byte(t.ExpectedStdout))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close zip: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func readOwn(fs files) (*Package, error) {
+
+	var e ownExercise
+
+	err := json.Unmarshal(fs[exerciseFileName], &e)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal %s: %w", exerciseFileName, err)
+	}
+
+	if e.Version != ownVersion {
+		return nil, fmt.Errorf("unsupported version: %d", e.Version)
+	}
+
+	p := &Package{
+		Exercise: &mycode.Exercise{
+			Title:           e.Title,
+			Description:     e.Description,
+			ReferenceSource: e.ReferenceSource,
+		},
+		LanguageSet: true,
+	}
+
+	err = enumValue(mycode.Language_value, e.Language,
+		(*int32)(&p.Exercise.Language))
+	if err != nil {
+		return nil, fmt.Errorf("exercise language: %w", err)
+	}
+
+	err = enumValue(mycode.ExerciseEstimator_value, e.Estimator,
+		(*int32)(&p.Exercise.Estimator))
+	if err != nil {
+		return nil, fmt.Errorf("exercise estimator: %w", err)
+	}
+
+	for i, ot := range e.Tests {
+		t := &mycode.Test{
+			Name:            ot.Name,
+			MaxDuration:     ot.MaxDuration,
+			MaxMemory:       ot.MaxMemory,
+			FloatAbsEpsilon: ot.FloatAbsEpsilon,
+			FloatRelEpsilon: ot.FloatRelEpsilon,
+			CheckerLanguage: ot.CheckerLanguage,
+			CheckerSource:   ot.CheckerSource,
+		}
+
+		err = enumValue(mycode.TestType_value, ot.Type, (*int32)(&t.Type))
+		if err != nil {
+			return nil, fmt.Errorf("test %d type: %w", i+1, err)
+		}
+
+		if ot.ComparisonMode != "" {
+			err = enumValue(mycode.ComparisonMode_value, ot.ComparisonMode,
+				(*int32)(&t.ComparisonMode))
+			if err != nil {
+				return nil, fmt.Errorf("test %d comparison mode: %w", i+1, err)
+			}
+		}
+
+		if ot.CheckerProtocol != "" {
+			err = enumValue(mycode.CheckerProtocol_value, ot.CheckerProtocol,
+				(*int32)(&t.CheckerProtocol))
+			if err != nil {
+				return nil, fmt.Errorf("test %d checker protocol: %w", i+1,
+					err)
+			}
+		}
+
+		stdin, exists := fs[fmt.Sprintf(inputPathFormat, i+1)]
+		if !exists {
+			return nil, fmt.Errorf("test %d input not found", i+1)
+		}
+
+		t.Stdin = string(stdin)
+		t.ExpectedStdout = string(fs[fmt.Sprintf(answerPathFormat, i+1)])
+
+		p.Tests = append(p.Tests, t)
+	}
+
+	return p, nil
+}
+
+func enumValue(values map[string]int32, name string, v *int32) error {
+	value, exists := values[name]
+	if !exists {
+		return fmt.Errorf("unknown value: %s", name)
+	}
+	*v = value
+	return nil
+}
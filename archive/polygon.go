@@ -0,0 +1,269 @@
+package archive
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/dimuls/mycode"
+)
+
+const polygonFileName = "problem.xml"
+
+type polygonProblem struct {
+	Names []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Statements []struct {
+		Language string `xml:"language,attr"`
+		Path     string `xml:"path,attr"`
+	} `xml:"statements>statement"`
+	Testsets []struct {
+		Name          string     `xml:"name,attr"`
+		TimeLimit     int64      `xml:"time-limit"`
+		MemoryLimit   uint64     `xml:"memory-limit"`
+		InputPattern  string     `xml:"input-path-pattern"`
+		AnswerPattern string     `xml:"answer-path-pattern"`
+		Tests         []struct{} `xml:"tests>test"`
+	} `xml:"judging>testset"`
+	Checker struct {
+		Name   string        `xml:"name,attr"`
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>checker"`
+	Interactor *struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>interactor"`
+}
+
+type polygonSource struct {
+	Path string `xml:"path,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// polygonLanguages are statement languages in preference order.
+var polygonLanguages = []string{"russian", "english"}
+
+// polygonStandardCheckers are testlib standard checkers, which are replaced
+// with comparison modes, so testlib isn't required to judge them.
+var polygonStandardCheckers = map[string]struct {
+	mode       mycode.ComparisonMode
+	absEpsilon float64
+}{
+	"std::fcmp.cpp":   {mode: mycode.ComparisonMode_ignore_trailing_whitespace},
+	"std::lcmp.cpp":   {mode: mycode.ComparisonMode_tokens},
+	"std::wcmp.cpp":   {mode: mycode.ComparisonMode_tokens},
+	"std::ncmp.cpp":   {mode: mycode.ComparisonMode_tokens},
+	"std::hcmp.cpp":   {mode: mycode.ComparisonMode_tokens},
+	"std::yesno.cpp":  {mode: mycode.ComparisonMode_case_insensitive_tokens},
+	"std::nyesno.cpp": {mode: mycode.ComparisonMode_case_insensitive_tokens},
+	"std::rcmp.cpp":   {mode: mycode.ComparisonMode_float_tokens, absEpsilon: 1.5e-5},
+	"std::rcmp4.cpp":  {mode: mycode.ComparisonMode_float_tokens, absEpsilon: 1e-4},
+	"std::rcmp6.cpp":  {mode: mycode.ComparisonMode_float_tokens, absEpsilon: 1e-6},
+	"std::rcmp9.cpp":  {mode: mycode.ComparisonMode_float_tokens, absEpsilon: 1e-9},
+}
+
+func readPolygon(fs files) (*Package, error) {
+
+	var pp polygonProblem
+
+	err := xml.Unmarshal(fs[polygonFileName], &pp)
+	if err != nil {
+		return nil, fmt.Errorf("XML unmarshal %s: %w", polygonFileName, err)
+	}
+
+	if len(pp.Testsets) == 0 {
+		return nil, fmt.Errorf("no testsets")
+	}
+
+	ts := pp.Testsets[0]
+	for _, t := range pp.Testsets {
+		if t.Name == "tests" {
+			ts = t
+		}
+	}
+
+	p := &Package{
+		Exercise: &mycode.Exercise{},
+	}
+
+	lang := polygonLanguage(pp)
+
+	for _, n := range pp.Names {
+		if n.Language == lang || p.Exercise.Title == "" {
+			p.Exercise.Title = n.Value
+		}
+	}
+
+	p.Exercise.Description = polygonDescription(fs, pp, lang)
+	if p.Exercise.Description == "" {
+		p.Exercise.Description = p.Exercise.Title
+	}
+
+	maxDuration := defaultMaxDuration
+	if ts.TimeLimit > 0 {
+		maxDuration = (time.Duration(ts.TimeLimit) * time.Millisecond).String()
+	}
+
+	maxMemory := defaultMaxMemory
+	if ts.MemoryLimit > 0 {
+		maxMemory = datasize.ByteSize(ts.MemoryLimit).String()
+	}
+
+	test := &mycode.Test{
+		MaxDuration: maxDuration,
+		MaxMemory:   maxMemory,
+	}
+
+	switch {
+	case pp.Interactor != nil:
+		test.Type = mycode.TestType_interactive
+		err = polygonSourceToTest(fs, pp.Interactor.Source, test)
+		if err != nil {
+			return nil, fmt.Errorf("interactor: %w", err)
+		}
+	case strings.HasPrefix(pp.Checker.Name, "std::"):
+		sc, exists := polygonStandardCheckers[pp.Checker.Name]
+		if !exists {
+			sc.mode = mycode.ComparisonMode_tokens
+		}
+		test.ComparisonMode = sc.mode
+		test.FloatAbsEpsilon = sc.absEpsilon
+		test.FloatRelEpsilon = sc.absEpsilon
+	case pp.Checker.Source.Path != "":
+		test.Type = mycode.TestType_checker
+		err = polygonSourceToTest(fs, pp.Checker.Source, test)
+		if err != nil {
+			return nil, fmt.Errorf("checker: %w", err)
+		}
+	default:
+		test.ComparisonMode = mycode.ComparisonMode_tokens
+	}
+
+	if ts.InputPattern == "" {
+		ts.InputPattern = inputPathFormat
+	}
+
+	if ts.AnswerPattern == "" {
+		ts.AnswerPattern = answerPathFormat
+	}
+
+	for i := range ts.Tests {
+		inputPath := fmt.Sprintf(ts.InputPattern, i+1)
+
+		stdin, exists := fs[inputPath]
+		if !exists {
+			return nil, fmt.Errorf("test %d input %s not found, "+
+				"package with generated tests should be full", i+1,
+				inputPath)
+		}
+
+		t := copyTest(test)
+		t.Name = path.Base(inputPath)
+		t.Stdin = string(stdin)
+		t.ExpectedStdout = string(fs[fmt.Sprintf(ts.AnswerPattern, i+1)])
+
+		p.Tests = append(p.Tests, t)
+	}
+
+	return p, nil
+}
+
+func polygonLanguage(pp polygonProblem) string {
+	for _, l := range polygonLanguages {
+		for _, n := range pp.Names {
+			if n.Language == l {
+				return l
+			}
+		}
+	}
+	if len(pp.Names) != 0 {
+		return pp.Names[0].Language
+	}
+	return ""
+}
+
+// polygonDescription builds description from legend, input and output
+// statement sections. Whole statement is used if there are no sections.
+func polygonDescription(fs files, pp polygonProblem, lang string) string {
+
+	var sections []string
+
+	for _, name := range []string{"legend", "input", "output", "notes"} {
+		s, exists := fs[path.Join("statement-sections", lang, name+".tex")]
+		if exists && len(strings.TrimSpace(string(s))) != 0 {
+			sections = append(sections, strings.TrimSpace(string(s)))
+		}
+	}
+
+	if len(sections) != 0 {
+		return strings.Join(sections, "\n\n")
+	}
+
+	for _, s := range pp.Statements {
+		if s.Language == lang {
+			if content, exists := fs[s.Path]; exists {
+				return string(content)
+			}
+		}
+	}
+
+	return ""
+}
+
+// polygonSourceToTest sets test checker or interactor from the package
+// source with its includes like files/testlib.h inlined.
+func polygonSourceToTest(fs files, s polygonSource, t *mycode.Test) error {
+
+	source, exists := fs[s.Path]
+	if !exists {
+		return fmt.Errorf("source %s not found", s.Path)
+	}
+
+	l, err := polygonSourceLanguage(s)
+	if err != nil {
+		return err
+	}
+
+	t.CheckerLanguage = l.String()
+	t.CheckerSource = string(source)
+
+	if l == mycode.Language_c || l == mycode.Language_cpp {
+		t.CheckerSource, err = inlineIncludes(fs, s.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.CheckerProtocol = mycode.CheckerProtocol_testlib_checker
+
+	return nil
+}
+
+// polygonSourceLanguage gets language from source type like cpp.g++17 or
+// python.3 falling back to the source file extension.
+func polygonSourceLanguage(s polygonSource) (mycode.Language, error) {
+
+	t := strings.ToLower(s.Type)
+
+	switch {
+	case strings.HasPrefix(t, "cpp"):
+		return mycode.Language_cpp, nil
+	case strings.HasPrefix(t, "c."):
+		return mycode.Language_c, nil
+	case strings.HasPrefix(t, "java"):
+		return mycode.Language_java, nil
+	case strings.HasPrefix(t, "python"):
+		return mycode.Language_python, nil
+	case strings.HasPrefix(t, "pas"), strings.HasPrefix(t, "delphi"):
+		return mycode.Language_pascal, nil
+	case strings.HasPrefix(t, "go"):
+		return mycode.Language_go, nil
+	default:
+		return sourceLanguage(s.Path)
+	}
+}
@@ -0,0 +1,192 @@
+package archive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+const polygonProblemXML = `<?xml version="1.0" encoding="utf-8"?>
+<problem revision="3" short-name="a-plus-b">
+    <names>
+        <name language="english" value="A+B"/>
+        <name language="russian" value="А+Б"/>
+    </names>
+    <statements>
+        <statement language="russian" path="statements/russian/problem.tex" type="application/x-tex"/>
+    </statements>
+    <judging cpu-name="Intel" input-file="" output-file="">
+        <testset name="tests">
+            <time-limit>2000</time-limit>
+            <memory-limit>268435456</memory-limit>
+            <test-count>2</test-count>
+            <input-path-pattern>tests/%02d</input-path-pattern>
+            <answer-path-pattern>tests/%02d.a</answer-path-pattern>
+            <tests>
+                <test method="manual"/>
+                <test method="manual"/>
+            </tests>
+        </testset>
+    </judging>
+    <assets>
+        <checker name="CHECKER" type="testlib">
+            <source path="files/check.cpp" type="cpp.g++17"/>
+        </checker>
+    </assets>
+</problem>
+`
+
+func TestImportPolygon(t *testing.T) {
+
+	fs := map[string]string{
+		"a-plus-b/problem.xml":                           polygonProblemXML,
+		"a-plus-b/statement-sections/russian/legend.tex": "Сложите числа.\n",
+		"a-plus-b/statement-sections/russian/input.tex":  "Два числа.",
+		"a-plus-b/statements/russian/problem.tex":        "whole statement",
+		"a-plus-b/files/check.cpp":                       "#include \"testlib.h\"\nint main() {}\n",
+		"a-plus-b/files/testlib.h":                       "// testlib\n",
+		"a-plus-b/tests/01":                              "1 2\n",
+		"a-plus-b/tests/01.a":                            "3\n",
+		"a-plus-b/tests/02":                              "2 2\n",
+	}
+
+	p, err := Import(zipFiles(t, fs))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if p.LanguageSet {
+		t.Error("language set")
+	}
+
+	wantExercise := &mycode.Exercise{
+		Title:       "А+Б",
+		Description: "Сложите числа.\n\nДва числа.",
+	}
+
+	if !reflect.DeepEqual(p.Exercise, wantExercise) {
+		t.Errorf("exercise = %+v, want %+v", p.Exercise, wantExercise)
+	}
+
+	checkerSource := "#line 1 \"testlib.h\"\n// testlib\n" +
+		"#line 2 \"check.cpp\"\nint main() {}\n"
+
+	wantTests := []*mycode.Test{{
+		Name:            "01",
+		Type:            mycode.TestType_checker,
+		MaxDuration:     "2s",
+		MaxMemory:       "256MB",
+		Stdin:           "1 2\n",
+		ExpectedStdout:  "3\n",
+		CheckerLanguage: mycode.Language_cpp.String(),
+		CheckerSource:   checkerSource,
+		CheckerProtocol: mycode.CheckerProtocol_testlib_checker,
+	}, {
+		Name:            "02",
+		Type:            mycode.TestType_checker,
+		MaxDuration:     "2s",
+		MaxMemory:       "256MB",
+		Stdin:           "2 2\n",
+		CheckerLanguage: mycode.Language_cpp.String(),
+		CheckerSource:   checkerSource,
+		CheckerProtocol: mycode.CheckerProtocol_testlib_checker,
+	}}
+
+	if !reflect.DeepEqual(p.Tests, wantTests) {
+		t.Errorf("tests = %+v, want %+v", p.Tests, wantTests)
+	}
+}
+
+func TestImportPolygonChecker(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		checker        string
+		files          map[string]string
+		wantType       mycode.TestType
+		wantMode       mycode.ComparisonMode
+		wantAbsEpsilon float64
+		wantErr        string
+	}{{
+		name:           "standard float checker",
+		checker:        `<checker name="std::rcmp6.cpp" type="testlib"/>`,
+		wantType:       mycode.TestType_simple,
+		wantMode:       mycode.ComparisonMode_float_tokens,
+		wantAbsEpsilon: 1e-6,
+	}, {
+		name:     "unknown standard checker",
+		checker:  `<checker name="std::new.cpp" type="testlib"/>`,
+		wantType: mycode.TestType_simple,
+		wantMode: mycode.ComparisonMode_tokens,
+	}, {
+		name: "missing include",
+		checker: `<checker name="CHECKER" type="testlib">
+			<source path="files/check.cpp" type="cpp.g++17"/></checker>`,
+		files: map[string]string{
+			"files/check.cpp": "#include \"testlib.h\"\n",
+		},
+		wantErr: "included testlib.h not found",
+	}, {
+		name: "include outside package",
+		checker: `<checker name="CHECKER" type="testlib">
+			<source path="files/check.cpp" type="cpp.g++17"/></checker>`,
+		files: map[string]string{
+			"files/check.cpp": "#include \"../../../etc/passwd\"\n",
+		},
+		wantErr: "not found in package",
+	}, {
+		name: "missing source",
+		checker: `<checker name="CHECKER" type="testlib">
+			<source path="files/check.cpp" type="cpp.g++17"/></checker>`,
+		wantErr: "source files/check.cpp not found",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			fs := map[string]string{
+				"problem.xml": `<problem><names><name language="english" value="A"/></names>
+					<judging><testset name="tests"><tests><test/></tests></testset></judging>
+					<assets>` + tt.checker + `</assets></problem>`,
+				"tests/01": "1\n",
+			}
+
+			for name, content := range tt.files {
+				fs[name] = content
+			}
+
+			p, err := Import(zipFiles(t, fs))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err,
+						tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+
+			test := p.Tests[0]
+
+			if test.Type != tt.wantType || test.ComparisonMode != tt.wantMode ||
+				test.FloatAbsEpsilon != tt.wantAbsEpsilon {
+
+				t.Errorf("test = %+v, want type %v, mode %v and abs "+
+					"epsilon %v", test, tt.wantType, tt.wantMode,
+					tt.wantAbsEpsilon)
+			}
+
+			if test.MaxDuration != defaultMaxDuration ||
+				test.MaxMemory != defaultMaxMemory {
+
+				t.Errorf("limits = %s, %s, want defaults", test.MaxDuration,
+					test.MaxMemory)
+			}
+		})
+	}
+}
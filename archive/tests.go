@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+const inputExt = ".in"
+
+// answerExts are expected stdout file extensions in preference order.
+var answerExts = []string{".out", ".ans", ".a"}
+
+// ReadTests reads tests from zip archive of 01.in and 01.out file pairs.
+// Tests are named after the files and ordered by name, numeric names are
+// ordered by number. Only stdins and expected stdouts are set, expected
+// stdout is empty if the answer file is missing.
+func ReadTests(data []byte) ([]*mycode.Test, error) {
+
+	fs, err := unzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ts    []*mycode.Test
+		names = map[string]string{}
+	)
+
+	for p, stdin := range fs {
+		if path.Ext(p) != inputExt {
+			continue
+		}
+
+		name := strings.TrimSuffix(path.Base(p), inputExt)
+
+		if other, exists := names[name]; exists {
+			return nil, fmt.Errorf("duplicate test %s: %s and %s", name,
+				other, p)
+		}
+
+		names[name] = p
+
+		t := &mycode.Test{
+			Name:  name,
+			Stdin: string(stdin),
+		}
+
+		for _, ext := range answerExts {
+			if stdout, exists := fs[strings.TrimSuffix(p, inputExt)+ext]; exists {
+				t.ExpectedStdout = string(stdout)
+				break
+			}
+		}
+
+		ts = append(ts, t)
+	}
+
+	if len(ts) == 0 {
+		return nil, fmt.Errorf("no %s files found", inputExt)
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		ni, errI := strconv.Atoi(ts[i].Name)
+		nj, errJ := strconv.Atoi(ts[j].Name)
+		if errI == nil && errJ == nil && ni != nj {
+			return ni < nj
+		}
+		return ts[i].Name < ts[j].Name
+	})
+
+	return ts, nil
+}
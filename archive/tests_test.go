@@ -0,0 +1,90 @@
+package archive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestReadTests(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []*mycode.Test
+		wantErr string
+	}{{
+		name: "numeric order and answer extensions",
+		files: map[string]string{
+			"10.in":  "10",
+			"10.out": "out",
+			"10.ans": "ans",
+			"2.in":   "2",
+			"2.ans":  "ans",
+			"2.a":    "a",
+			"1.in":   "1",
+			"1.a":    "a",
+		},
+		want: []*mycode.Test{
+			{Name: "1", Stdin: "1", ExpectedStdout: "a"},
+			{Name: "2", Stdin: "2", ExpectedStdout: "ans"},
+			{Name: "10", Stdin: "10", ExpectedStdout: "out"},
+		},
+	}, {
+		name: "names order and missing answer",
+		files: map[string]string{
+			"tests/b.in":  "b",
+			"tests/a.in":  "a",
+			"tests/a.out": "",
+			"readme.txt":  "ignored",
+		},
+		want: []*mycode.Test{
+			{Name: "a", Stdin: "a"},
+			{Name: "b", Stdin: "b"},
+		},
+	}, {
+		name: "duplicate",
+		files: map[string]string{
+			"a/1.in": "1",
+			"b/1.in": "1",
+		},
+		wantErr: "duplicate test 1",
+	}, {
+		name: "no inputs",
+		files: map[string]string{
+			"1.out": "1",
+		},
+		wantErr: "no .in files found",
+	}, {
+		name: "parent directory",
+		files: map[string]string{
+			"../1.in": "1",
+		},
+		wantErr: "invalid file name",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := ReadTests(zipFiles(t, tt.files))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err,
+						tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("read tests: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tests = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package blob stores large contents like tests stdins and runs outputs by
+// their SHA-256 hashes. Contents are stored in the local filesystem or in
+// the S3 compatible storage.
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type Storage interface {
+	// Put stores content and returns its key. Storing the same content
+	// again is no-op.
+	Put(ctx context.Context, content []byte) (key string, err error)
+	// Get returns content by key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete deletes content by key. Deleting missing content is no-op.
+	Delete(ctx context.Context, key string) error
+	// Walk calls fn with the key and the last put time of every stored
+	// content until fn returns error.
+	Walk(ctx context.Context, fn func(key string, putAt time.Time) error) error
+}
+
+// Key returns content key, which is its SHA-256 hash in hex.
+func Key(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func checkKey(key string) error {
+	b, err := hex.DecodeString(key)
+	if err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid key: %q", key)
+	}
+	return nil
+}
+
+// Open opens storage by URI. Supported URIs are
+//
+//	file:///var/lib/mycode/blobs
+//	s3://access_key:secret_key@host:port/bucket?region=us-east-1&insecure=true
+//
+// S3 URI path after the bucket is used as keys prefix. Set insecure to use
+// HTTP, for example with local MinIO.
+func Open(uri string) (Storage, error) {
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parse URI: %w", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewFS(u.Path)
+
+	case "s3":
+		c := S3Config{
+			Endpoint: "https://" + u.Host,
+			Region:   u.Query().Get("region"),
+		}
+
+		if u.Query().Get("insecure") == "true" {
+			c.Endpoint = "http://" + u.Host
+		}
+
+		if u.User != nil {
+			c.AccessKey = u.User.Username()
+			c.SecretKey, _ = u.User.Password()
+		}
+
+		ps := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+
+		c.Bucket = ps[0]
+		if len(ps) == 2 {
+			c.Prefix = ps[1] + "/"
+		}
+
+		return NewS3(c)
+
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+}
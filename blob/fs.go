@@ -0,0 +1,144 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FS stores contents in directory. Content is stored in file named by key
+// in subdirectory named by the first two key characters, so directories
+// don't grow too large.
+type FS struct {
+	path string
+}
+
+func NewFS(path string) (*FS, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("make blobs directory: %w", err)
+	}
+
+	return &FS{path: path}, nil
+}
+
+func (fs *FS) keyPath(key string) string {
+	return filepath.Join(fs.path, key[:2], key)
+}
+
+func (fs *FS) Put(ctx context.Context, content []byte) (string, error) {
+
+	key := Key(content)
+	keyPath := fs.keyPath(key)
+
+	// Put time of the stored content is updated, so it isn't deleted as
+	// unreferenced right after the new reference is added.
+	now := time.Now()
+
+	err := os.Chtimes(keyPath, now, now)
+	if err == nil {
+		return key, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(keyPath), 0755)
+	if err != nil {
+		return "", fmt.Errorf("make blob directory: %w", err)
+	}
+
+	// Content is written to temp file and renamed, so concurrent readers
+	// never see partially written blob.
+	f, err := ioutil.TempFile(filepath.Dir(keyPath), key+".*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+
+	_, err = f.Write(content)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("close temp file: %w", err)
+	}
+
+	err = os.Rename(f.Name(), keyPath)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return key, nil
+}
+
+func (fs *FS) Get(ctx context.Context, key string) ([]byte, error) {
+
+	err := checkKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(fs.keyPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read blob file: %w", err)
+	}
+
+	return content, nil
+}
+
+func (fs *FS) Delete(ctx context.Context, key string) error {
+
+	err := checkKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(fs.keyPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove blob file: %w", err)
+	}
+
+	return nil
+}
+
+// Walk skips files which aren't blobs, like temp files of the interrupted
+// puts.
+func (fs *FS) Walk(ctx context.Context,
+	fn func(key string, putAt time.Time) error) error {
+
+	return filepath.Walk(fs.path, func(p string, info os.FileInfo,
+		err error) error {
+
+		if err != nil {
+			// Blob deleted concurrently.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() || checkKey(info.Name()) != nil {
+			return nil
+		}
+
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		return fn(info.Name(), info.ModTime())
+	})
+}
@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "mycode-blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	contents := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte("large content\n"), 10000),
+	}
+
+	keys := map[string]bool{}
+
+	for _, c := range contents {
+		key, err := fs.Put(ctx, c)
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+
+		if key != Key(c) {
+			t.Errorf("put key = %s, want %s", key, Key(c))
+		}
+
+		// Put of the same content is no-op.
+		key2, err := fs.Put(ctx, c)
+		if err != nil {
+			t.Fatalf("put again: %v", err)
+		}
+
+		if key2 != key {
+			t.Errorf("put again key = %s, want %s", key2, key)
+		}
+
+		got, err := fs.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+
+		if !bytes.Equal(got, c) {
+			t.Errorf("get content = %q, want %q", got, c)
+		}
+
+		keys[key] = true
+	}
+
+	// Temp file of the interrupted put isn't blob.
+	err = ioutil.WriteFile(filepath.Join(dir, Key(nil)[:2],
+		Key(nil)+".123"), []byte("partial"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walked := map[string]bool{}
+
+	err = fs.Walk(ctx, func(key string, putAt time.Time) error {
+		if time.Since(putAt) > time.Minute {
+			t.Errorf("walk %s put at %s", key, putAt)
+		}
+		walked[key] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+
+	if len(walked) != len(keys) {
+		t.Errorf("walked %d keys, want %d", len(walked), len(keys))
+	}
+
+	for key := range keys {
+		if !walked[key] {
+			t.Errorf("key %s not walked", key)
+		}
+
+		err = fs.Delete(ctx, key)
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+
+		_, err = fs.Get(ctx, key)
+		if err != ErrNotFound {
+			t.Errorf("get deleted error = %v, want %v", err, ErrNotFound)
+		}
+
+		err = fs.Delete(ctx, key)
+		if err != nil {
+			t.Errorf("delete deleted: %v", err)
+		}
+	}
+
+	_, err = fs.Get(ctx, "../../etc/passwd")
+	if err == nil {
+		t.Error("get invalid key succeeded")
+	}
+}
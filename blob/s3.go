@@ -0,0 +1,335 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3Timeout       = 30 * time.Second
+
+	// s3MaxErrorBody limits error response body read into the error.
+	s3MaxErrorBody = 1 << 10
+)
+
+type S3Config struct {
+	// Endpoint is scheme and host, like https://storage.yandexcloud.net or
+	// http://localhost:9000 for local MinIO.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 stores contents in S3 compatible storage bucket. Requests are signed
+// with AWS Signature Version 4 and use path style addressing, which is
+// supported by MinIO and the other S3 compatible storages.
+type S3 struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3(c S3Config) (*S3, error) {
+
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("empty bucket")
+	}
+
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, fmt.Errorf("empty credentials")
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint: %s", c.Endpoint)
+	}
+
+	if c.Region == "" {
+		c.Region = s3DefaultRegion
+	}
+
+	c.Endpoint = u.Scheme + "://" + u.Host
+
+	return &S3{
+		config: c,
+		client: &http.Client{Timeout: s3Timeout},
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, content []byte) (string, error) {
+
+	key := Key(content)
+
+	resp, err := s.do(ctx, http.MethodPut, s.objectPath(key), nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", s3Error(resp)
+	}
+
+	return key, nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+
+	err := checkKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.objectPath(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, s3Error(resp)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	return content, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+
+	err := checkKey(key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.objectPath(key), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Deleting missing object succeeds too.
+	if resp.StatusCode != http.StatusNoContent &&
+		resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+
+	return nil
+}
+
+// s3ListResult is ListObjectsV2 response.
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// Walk lists objects with the prefix. Objects with keys which aren't blob
+// keys are skipped.
+func (s *S3) Walk(ctx context.Context,
+	fn func(key string, putAt time.Time) error) error {
+
+	var token string
+
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", s.config.Prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		r, err := s.list(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range r.Contents {
+			key := strings.TrimPrefix(c.Key, s.config.Prefix)
+			if checkKey(key) != nil {
+				continue
+			}
+			err = fn(key, c.LastModified)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !r.IsTruncated || r.NextContinuationToken == "" {
+			return nil
+		}
+
+		token = r.NextContinuationToken
+	}
+}
+
+func (s *S3) list(ctx context.Context, q url.Values) (*s3ListResult, error) {
+
+	resp, err := s.do(ctx, http.MethodGet, "/"+s.config.Bucket, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+
+	var r s3ListResult
+
+	err = xml.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return nil, fmt.Errorf("XML decode list result: %w", err)
+	}
+
+	return &r, nil
+}
+
+func (s *S3) objectPath(key string) string {
+	return "/" + s.config.Bucket + "/" + s.config.Prefix + key
+}
+
+func (s *S3) do(ctx context.Context, method, path string, query url.Values,
+	body []byte) (*http.Response, error) {
+
+	u, err := url.Parse(s.config.Endpoint + path)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+
+	// Signature requires spaces encoded as %20. Plus sign itself is
+	// encoded as %2B, so all the remaining ones are spaces.
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.ContentLength = int64(len(body))
+
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// sign signs request with AWS Signature Version 4, see
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+
+	const (
+		algorithm = "AWS4-HMAC-SHA256"
+		service   = "s3"
+	)
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var headerNames []string
+	for name := range req.Header {
+		headerNames = append(headerNames, strings.ToLower(name))
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteByte(':')
+		canonicalHeaders.WriteString(strings.TrimSpace(req.Header.Get(name)))
+		canonicalHeaders.WriteByte('\n')
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.config.Region, service,
+		"aws4_request"}, "/")
+
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", algorithm,
+		s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, s3MaxErrorBody))
+	return fmt.Errorf("unexpected response status %s: %s", resp.Status,
+		strings.TrimSpace(string(body)))
+}
@@ -2,17 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
 	"github.com/sirupsen/logrus"
 
 	"github.com/dimuls/mycode"
@@ -20,48 +25,110 @@ import (
 )
 
 const (
-	memoryLimitBytes = 107_374_182_400 // 100MB
+	modeCompile = "compile"
+	modeRun     = "run"
+
+	// addressSpaceReserve is added to RLIMIT_AS since Go and Java runtimes
+	// reserve much more virtual memory than they actually use. Memory limit
+	// itself is enforced with cgroup.
+	addressSpaceReserve = 1024 * datasize.MB
+
+	cgroupMemParent = "/sys/fs/cgroup/memory/NSJAIL"
+
+	// sandboxLogsReserve is added to stderr limit for nsjail logs and
+	// /usr/bin/time stats, which are written to stderr besides program one.
+	sandboxLogsReserve = 64 * datasize.KB
+
+	timeFormat = "%M %x %U %S"
 )
 
+type limits struct {
+	time   time.Duration
+	memory datasize.ByteSize
+	output datasize.ByteSize
+}
+
 func main() {
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 
-	if len(os.Args) < 3 {
-		logrus.WithField("args", os.Args).
-			Fatal("expected 3 or more  args")
-	}
-
-	languageID, exists := mycode.Language_value[os.Args[1]]
+	var (
+		mode         string
+		languageName string
+		srcPath      string
+		artifactPath string
+		timeLimit    time.Duration
+		memoryLimit  string
+		outputLimit  string
+		filesPath    string
+		iop          ioPaths
+	)
+
+	flag.StringVar(&mode, "mode", "", "mode: compile or run")
+	flag.StringVar(&languageName, "language", "", "source language")
+	flag.StringVar(&srcPath, "source", "", "source file path, compile mode only")
+	flag.StringVar(&artifactPath, "artifact", "", "artifact directory path")
+	flag.DurationVar(&timeLimit, "time-limit", 10*time.Second, "time limit, run mode only")
+	flag.StringVar(&memoryLimit, "memory-limit", "256MB", "memory limit, run mode only")
+	flag.StringVar(&outputLimit, "output-limit", "64MB", "stdout and stderr size limit each, run mode only")
+	flag.StringVar(&filesPath, "files", "", "directory available to program read only, run mode only")
+	flag.StringVar(&iop.stdin, "stdin-path", "", "stdin file or FIFO path, empty stdin if not set, run mode only")
+	flag.StringVar(&iop.stdout, "stdout-path", "", "stdout file or FIFO path, run mode only")
+	flag.BoolVar(&iop.stdoutFirst, "open-stdout-first", false, "open stdout path before stdin path, run mode only")
+	flag.Parse()
+
+	languageID, exists := mycode.Language_value[languageName]
 	if !exists {
-		logrus.WithField("language", os.Args[1]).
+		logrus.WithField("language", languageName).
 			Fatal("invalid language")
 	}
 
 	language := mycode.Language(languageID)
 
-	srcBytes, err := ioutil.ReadFile(os.Args[2])
-	if err != nil {
-		logrus.WithField("src_path", os.Args[2]).
-			Fatal("failed to read source")
+	if artifactPath == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
 	}
 
-	src := string(srcBytes)
+	switch mode {
+	case modeCompile:
+		compile(language, srcPath, artifactPath)
+	case modeRun:
+		l := limits{time: timeLimit}
 
-	stdin := os.Args[3]
+		err := l.memory.UnmarshalText([]byte(memoryLimit))
+		if err != nil {
+			logrus.WithError(err).WithField("memory_limit", memoryLimit).
+				Fatal("invalid memory limit")
+		}
 
-	r, err := docker.Prepare(language, src)
-	if err != nil {
-		logrus.WithError(err).Fatal("failed to prepare run")
+		err = l.output.UnmarshalText([]byte(outputLimit))
+		if err != nil {
+			logrus.WithError(err).WithField("output_limit", outputLimit).
+				Fatal("invalid output limit")
+		}
+
+		if l.time <= 0 || l.memory == 0 || l.output == 0 {
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+
+		run(language, artifactPath, iop, l, filesPath, flag.Args())
+	default:
+		logrus.WithField("mode", mode).Fatal("invalid mode")
 	}
+}
 
-	srcPath, err := r.SourcePath()
+func compile(language mycode.Language, srcPath, artifactPath string) {
+
+	srcBytes, err := ioutil.ReadFile(srcPath)
 	if err != nil {
-		logrus.WithError(err).Fatal("failed to get source path")
+		logrus.WithField("src_path", srcPath).
+			Fatal("failed to read source")
 	}
 
-	additionalBinds, err := r.AdditionalBinds()
+	r, err := docker.Prepare(language, string(srcBytes))
 	if err != nil {
-		logrus.WithError(err).Fatal("failed to get additional binds")
+		logrus.WithError(err).Fatal("failed to prepare run")
 	}
 
 	compileCmd, err := r.CompileCommand()
@@ -69,29 +136,60 @@ func main() {
 		logrus.WithError(err).Fatal("failed to get compile command")
 	}
 
+	c := &mycode.Compilation{}
+
 	if len(compileCmd) != 0 {
+		runSrcPath, err := r.SourcePath()
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to get source path")
+		}
+
 		cmd := exec.Command(compileCmd[0], compileCmd[1:]...)
-		cmd.Dir = srcPath
+		cmd.Dir = runSrcPath
 
 		var stdOutBuf, stdErrBuf bytes.Buffer
 
 		cmd.Stdout = &stdOutBuf
 		cmd.Stderr = &stdErrBuf
 
-		err := cmd.Run()
+		err = cmd.Run()
+
+		c.Failed = err != nil
+		c.Stdout = stdOutBuf.String()
+		c.Stderr = stdErrBuf.String()
+	}
+
+	if !c.Failed {
+		err = docker.Save(r, artifactPath)
 		if err != nil {
-			err = printRun(&mycode.Run{
-				Duration:   "0",
-				UsedMemory: "0",
-				Stdout:     stdOutBuf.String(),
-				Stderr:     stdErrBuf.String(),
-			})
-			if err != nil {
-				logrus.WithError(err).Fatal("failed to print run")
-			}
+			logrus.WithError(err).Fatal("failed to save artifact")
 		}
 	}
 
+	err = printMessage(c)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to print compilation")
+	}
+}
+
+func run(language mycode.Language, artifactPath string, iop ioPaths,
+	l limits, filesPath string, programArgs []string) {
+
+	r, err := docker.Restore(language, artifactPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to restore run")
+	}
+
+	srcPath, err := r.SourcePath()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to get source path")
+	}
+
+	additionalBinds, err := r.AdditionalBinds()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to get additional binds")
+	}
+
 	runCmd, err := r.RunCommand()
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to get run command")
@@ -102,9 +200,21 @@ func main() {
 			Fatal("run preparator returned empty run command")
 	}
 
+	err = os.MkdirAll(cgroupMemParent, 0755)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to make cgroup memory parent")
+	}
+
+	// Time limit is checked against CPU time, wall time limit only stops
+	// sleeping or blocked programs.
 	args := []string{
 		"--max_cpus", "1",
-		"--rlimit_as", strconv.Itoa(memoryLimitBytes),
+		"--rlimit_cpu", strconv.Itoa(int(math.Ceil(l.time.Seconds()))),
+		"--time_limit", strconv.Itoa(int(math.Ceil(
+			(l.time * docker.WallTimeLimitFactor).Seconds()))),
+		"--rlimit_as", strconv.FormatUint(
+			uint64(math.Ceil((l.memory + addressSpaceReserve).MBytes())), 10),
+		"--cgroup_mem_max", strconv.FormatUint(l.memory.Bytes(), 10),
 		"--user", "99999", "--group", "99999",
 		"--bindmount_ro", "/lib",
 		"--bindmount_ro", "/usr/lib",
@@ -115,68 +225,301 @@ func main() {
 		args = append(args, "--bindmount_ro", ab)
 	}
 
+	if filesPath != "" {
+		args = append(args, "--bindmount_ro", filesPath)
+	}
+
 	args = append(args,
 		"--bindmount", srcPath,
 		"--cwd", srcPath,
 		"--",
-		"/usr/bin/time", "-q", "-f", "%M", "--")
+		"/usr/bin/time", "-q", "-f", timeFormat, "--")
 
 	args = append(args, runCmd...)
+	args = append(args, programArgs...)
+
+	// Program is killed as soon as its output exceeds the limit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/usr/bin/nsjail", args...)
+
+	// Program stderr limit is checked after sandbox logs are stripped.
+	stdOutBuf := &outputBuffer{limit: int(l.output.Bytes()), onExceed: cancel}
+	stdErrBuf := &outputBuffer{
+		limit:    int((l.output + sandboxLogsReserve).Bytes()),
+		onExceed: cancel,
+	}
 
-	cmd := exec.Command("/usr/bin/nsjail", args...)
+	cmd.Stdout = stdOutBuf
+	cmd.Stderr = stdErrBuf
 
-	var stdOutBuf, stdErrBuf bytes.Buffer
+	stdinFile, stdoutFile, err := iop.open()
+	if err != nil {
+		printInternalError(fmt.Errorf("open io paths: %w", err),
+			stdOutBuf.Buffer, stdErrBuf.Buffer)
+		return
+	}
+
+	if stdinFile != nil {
+		defer stdinFile.Close()
+		cmd.Stdin = stdinFile
+	}
 
-	cmd.Stdin = strings.NewReader(stdin)
-	cmd.Stdout = &stdOutBuf
-	cmd.Stderr = &stdErrBuf
+	if stdoutFile != nil {
+		defer stdoutFile.Close()
+		cmd.Stdout = stdoutFile
+	}
 
 	start := time.Now()
 	err = cmd.Run()
-	duration := time.Since(start)
+	wallDuration := time.Since(start)
+
+	printOutputLimitExceeded := func(stdErr string) {
+		err := printMessage(&mycode.Run{
+			Verdict: mycode.Verdict_output_limit_exceeded,
+			Duration: (cmd.ProcessState.UserTime() +
+				cmd.ProcessState.SystemTime()).String(),
+			WallDuration: wallDuration.String(),
+			UsedMemory:   datasize.ByteSize(0).String(),
+			Stdout:       stdOutBuf.String(),
+			Stderr: mycode.TruncateOutput(stdErr,
+				int(l.output.Bytes())),
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to print run")
+		}
+	}
+
+	if stdOutBuf.exceeded || stdErrBuf.exceeded {
+		printOutputLimitExceeded(stripSandboxLogs(stdErrBuf.Buffer.String()))
+		return
+	}
+
+	// Killed by wall time limit program has no /usr/bin/time stats, so CPU
+	// time is taken from nsjail rusage, which includes reaped program.
+	if timeLimitRe.Match(stdErrBuf.Bytes()) {
+		err = printMessage(&mycode.Run{
+			Verdict: mycode.Verdict_time_limit_exceeded,
+			Duration: (cmd.ProcessState.UserTime() +
+				cmd.ProcessState.SystemTime()).String(),
+			WallDuration: wallDuration.String(),
+			UsedMemory:   datasize.ByteSize(0).String(),
+			Stdout:       stdOutBuf.String(),
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to print run")
+		}
+		return
+	}
+
 	if err != nil {
-		if !okStdErr(stdErrBuf) {
-			logrus.WithError(err).WithFields(logrus.Fields{
-				"stdout": stdOutBuf.String(),
-				"stderr": stdErrBuf.String(),
-			}).Fatal("failed to run")
+		if _, ok := err.(*exec.ExitError); !ok || !okStdErr(stdErrBuf.Buffer) {
+			printInternalError(fmt.Errorf("run nsjail: %w", err),
+				stdOutBuf.Buffer, stdErrBuf.Buffer)
+			return
 		}
 	}
 
-	stdErr, memoryUsageKB, err := parseStdErr(stdErrBuf)
+	stdErr, ts, err := parseStdErr(stdErrBuf.Buffer)
 	if err != nil {
-		logrus.WithError(err).Fatal("failed to parse stderr")
+		printInternalError(fmt.Errorf("parse stderr: %w", err),
+			stdOutBuf.Buffer, stdErrBuf.Buffer)
+		return
+	}
+
+	if len(stdErr) > int(l.output.Bytes()) {
+		printOutputLimitExceeded(stdErr)
+		return
+	}
+
+	usedMemory := datasize.ByteSize(ts.memoryUsageKB) * datasize.KB
+
+	run := &mycode.Run{
+		Verdict:      mycode.Verdict_accepted,
+		Duration:     ts.cpuTime.String(),
+		WallDuration: wallDuration.String(),
+		UsedMemory:   usedMemory.String(),
+		Stdout:       stdOutBuf.String(),
+		Stderr:       stdErr,
+		ExitCode:     int32(ts.exitCode),
+	}
+
+	// /usr/bin/time exits with 128 + signal number when program is killed
+	// by signal, and nsjail passes its exit code through.
+	if ts.exitCode == 0 && cmd.ProcessState.ExitCode() > 128 {
+		run.Signal = int32(cmd.ProcessState.ExitCode() - 128)
+	}
+
+	// Kernel sends SIGKILL both at the CPU time hard limit and from cgroup
+	// OOM killer, so killed program is judged by the used resources.
+	switch {
+	case run.Signal == int32(syscall.SIGXCPU) || ts.cpuTime >= l.time:
+		run.Verdict = mycode.Verdict_time_limit_exceeded
+	case run.Signal == int32(syscall.SIGKILL) && usedMemory >= l.memory:
+		run.Verdict = mycode.Verdict_memory_limit_exceeded
+	case run.ExitCode != 0 || run.Signal != 0:
+		run.Verdict = mycode.Verdict_runtime_error
+	}
+
+	err = printMessage(run)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to print run")
+	}
+}
+
+// ioPaths are program stdin and stdout paths. Stdin is file written by
+// runner and streamed to the program, so it isn't limited by the arguments
+// size. They are FIFOs when program interacts with another one, so they are
+// opened in the order opposite to the other program one, otherwise both
+// programs block forever.
+type ioPaths struct {
+	stdin       string
+	stdout      string
+	stdoutFirst bool
+}
+
+func (iop ioPaths) open() (stdin, stdout *os.File, err error) {
+
+	openStdin := func() error {
+		if iop.stdin == "" {
+			return nil
+		}
+		stdin, err = os.Open(iop.stdin)
+		if err != nil {
+			return fmt.Errorf("open stdin: %w", err)
+		}
+		return nil
+	}
+
+	openStdout := func() error {
+		if iop.stdout == "" {
+			return nil
+		}
+		stdout, err = os.OpenFile(iop.stdout, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("open stdout: %w", err)
+		}
+		return nil
+	}
+
+	opens := []func() error{openStdin, openStdout}
+
+	if iop.stdoutFirst {
+		opens[0], opens[1] = opens[1], opens[0]
+	}
+
+	for _, open := range opens {
+		err = open()
+		if err != nil {
+			if stdin != nil {
+				stdin.Close()
+			}
+			if stdout != nil {
+				stdout.Close()
+			}
+			return nil, nil, err
+		}
 	}
 
-	err = printRun(&mycode.Run{
-		Duration:   duration.String(),
-		UsedMemory: (datasize.ByteSize(memoryUsageKB) * datasize.KB).String(),
-		Stdout:     stdOutBuf.String(),
-		Stderr:     stdErr,
+	return stdin, stdout, nil
+}
+
+// outputBuffer keeps program output up to the limit and discards the rest,
+// so program can't exhaust runner memory. Exceeding the limit calls
+// onExceed once.
+type outputBuffer struct {
+	bytes.Buffer
+	limit    int
+	exceeded bool
+	onExceed func()
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return len(p), nil
+	}
+
+	// Byte after the limit is kept, so String sees output is truncated.
+	if rest := b.limit - b.Len(); len(p) > rest {
+		b.Buffer.Write(p[:rest+1])
+		b.exceeded = true
+		b.onExceed()
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
+
+// String returns output marked truncated if the limit is exceeded.
+func (b *outputBuffer) String() string {
+	return mycode.TruncateOutput(b.Buffer.String(), b.limit)
+}
+
+// printInternalError reports sandbox failure as run with internal error
+// verdict, so it is stored instead of lost with failed message.
+func printInternalError(err error, stdOut, stdErr bytes.Buffer) {
+
+	logrus.WithError(err).WithFields(logrus.Fields{
+		"stdout": stdOut.String(),
+		"stderr": stdErr.String(),
+	}).Error("failed to run")
+
+	err = printMessage(&mycode.Run{
+		Verdict: mycode.Verdict_internal_error,
+		Stderr:  err.Error(),
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to print run")
 	}
 }
 
-var exitWithStatusRe = regexp.MustCompile(`exited with status`)
+var timeLimitRe = regexp.MustCompile(`run time >= time limit`)
+
+var exitRe = regexp.MustCompile(`exited with status|terminated with signal`)
 
 func okStdErr(stdErr bytes.Buffer) bool {
 	lines := strings.Split(strings.TrimSpace(stdErr.String()), "\n")
 	if len(lines) == 0 {
 		return false
 	}
-	return exitWithStatusRe.MatchString(lines[len(lines)-1])
+	return exitRe.MatchString(lines[len(lines)-1])
 }
 
 var executingRe = regexp.MustCompile(`Executing '/usr/bin/time' for`)
 
-func parseStdErr(stdErr bytes.Buffer) (string, int, error) {
+// stripSandboxLogs strips nsjail logs preceding program stderr. Stderr of
+// the killed program has no trailing logs and time stats.
+func stripSandboxLogs(stdErr string) string {
+	loc := executingRe.FindStringIndex(stdErr)
+	if loc == nil {
+		return stdErr
+	}
+
+	i := strings.IndexByte(stdErr[loc[1]:], '\n')
+	if i < 0 {
+		return ""
+	}
+
+	return stdErr[loc[1]+i+1:]
+}
+
+type timeStats struct {
+	memoryUsageKB int
+	exitCode      int
+	cpuTime       time.Duration
+}
+
+// parseStdErr separates program stderr from nsjail logs and /usr/bin/time
+// stats line formatted with timeFormat.
+func parseStdErr(stdErr bytes.Buffer) (string, timeStats, error) {
+
+	var ts timeStats
 
 	lines := strings.Split(strings.TrimSpace(stdErr.String()), "\n")
 
 	if len(lines) < 3 {
-		return "", 0, fmt.Errorf("expected at least 3 lines")
+		return "", ts, fmt.Errorf("expected at least 3 lines")
 	}
 
 	lines = lines[:len(lines)-1]
@@ -188,22 +531,45 @@ func parseStdErr(stdErr bytes.Buffer) (string, int, error) {
 		}
 	}
 
-	memoryUsageStr := lines[len(lines)-1]
+	if len(lines) == 0 {
+		return "", ts, fmt.Errorf("time stats not found")
+	}
+
+	stats := strings.Fields(lines[len(lines)-1])
 	lines = lines[:len(lines)-1]
 
-	memoryUsage, err := strconv.Atoi(memoryUsageStr)
+	if len(stats) != 4 {
+		return "", ts, fmt.Errorf("unexpected time stats format")
+	}
+
+	var err error
+
+	ts.memoryUsageKB, err = strconv.Atoi(stats[0])
+	if err != nil {
+		return "", ts, fmt.Errorf("parse memory usage: %w", err)
+	}
+
+	ts.exitCode, err = strconv.Atoi(stats[1])
 	if err != nil {
-		return "", 0, fmt.Errorf("parse memory usage: %w", err)
+		return "", ts, fmt.Errorf("parse exit code: %w", err)
+	}
+
+	for _, s := range stats[2:] {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "", ts, fmt.Errorf("parse CPU time: %w", err)
+		}
+		ts.cpuTime += time.Duration(seconds * float64(time.Second))
 	}
 
-	return strings.Join(lines, "\n"), memoryUsage, nil
+	return strings.Join(lines, "\n"), ts, nil
 }
 
-func printRun(run *mycode.Run) error {
+func printMessage(m proto.Message) error {
 
-	err := (&jsonpb.Marshaler{}).Marshal(os.Stdout, run)
+	err := (&jsonpb.Marshaler{}).Marshal(os.Stdout, m)
 	if err != nil {
-		return fmt.Errorf("JSON marshal run to stdout: %w", err)
+		return fmt.Errorf("JSON marshal message to stdout: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestOutputBuffer(t *testing.T) {
+
+	exceeded := 0
+
+	b := &outputBuffer{limit: 5, onExceed: func() { exceeded++ }}
+
+	for _, p := range []string{"abc", "de", "", "fgh", "ijk"} {
+		n, err := b.Write([]byte(p))
+		if err != nil || n != len(p) {
+			t.Fatalf("write %q = %d, %v", p, n, err)
+		}
+	}
+
+	if !b.exceeded || exceeded != 1 {
+		t.Errorf("exceeded = %v, called %d times, want once", b.exceeded,
+			exceeded)
+	}
+
+	if got, want := b.String(), "abcde"+mycode.TruncatedMarker; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	b = &outputBuffer{limit: 5, onExceed: func() { exceeded++ }}
+
+	b.Write([]byte("abcde"))
+
+	if b.exceeded {
+		t.Error("exceeded at the limit")
+	}
+
+	if got := b.String(); got != "abcde" {
+		t.Errorf("output = %q, want %q", got, "abcde")
+	}
+}
+
+func TestStripSandboxLogs(t *testing.T) {
+
+	logs := "[I][2021-01-01T00:00:00+0000] Mode: STANDALONE_ONCE\n" +
+		"[I][2021-01-01T00:00:00+0000] Executing '/usr/bin/time' for '[STANDALONE MODE]'\n"
+
+	tests := []struct {
+		name   string
+		stdErr string
+		want   string
+	}{{
+		name:   "program stderr",
+		stdErr: logs + "error\n",
+		want:   "error\n",
+	}, {
+		name:   "empty program stderr",
+		stdErr: logs,
+		want:   "",
+	}, {
+		name:   "no logs",
+		stdErr: "error\n",
+		want:   "error\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripSandboxLogs(tt.stdErr)
+			if got != tt.want {
+				t.Errorf("stderr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStdErr(t *testing.T) {
+
+	stdErr := "[I] Mode: STANDALONE_ONCE\n" +
+		"[I] Executing '/usr/bin/time' for '[STANDALONE MODE]'\n" +
+		"line 1\nline 2\n" +
+		"2048 1 0.25 0.05\n" +
+		"[I] pid=1 ([STANDALONE MODE]) exited with status: 1\n"
+
+	got, ts, err := parseStdErr(*bytes.NewBufferString(stdErr))
+	if err != nil {
+		t.Fatalf("parse stderr: %v", err)
+	}
+
+	if got != "line 1\nline 2" {
+		t.Errorf("stderr = %q, want program lines", got)
+	}
+
+	if ts.memoryUsageKB != 2048 || ts.exitCode != 1 ||
+		ts.cpuTime.Milliseconds() != 300 {
+
+		t.Errorf("time stats = %+v", ts)
+	}
+}
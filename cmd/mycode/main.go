@@ -0,0 +1,260 @@
+// Command mycode runs API and runner in single process connected by
+// in-memory transport, for single server installs without RabbitMQ.
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/rs/cors"
+	"github.com/sirupsen/logrus"
+	"github.com/twitchtv/twirp"
+
+	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/blob"
+	"github.com/dimuls/mycode/docker"
+	"github.com/dimuls/mycode/mem"
+	"github.com/dimuls/mycode/pg"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		postgresURI             string
+		jwtSecret               string
+		listenAddress           string
+		dockerHost              string
+		codeHandlingParallelism int
+		runHandlingParallelism  int
+		queueCapacity           int
+		artifactsPath           string
+		artifactTTL             time.Duration
+		blobURI                 string
+		blobThreshold           int
+		outputLimit             string
+		storedOutputLimit       string
+		codesRate               float64
+		codesBurst              int
+	)
+
+	flag.StringVar(&postgresURI, "postgres-uri", "", "postgres URI")
+	flag.StringVar(&jwtSecret, "jwt-secret", "", "JWT token secret")
+	flag.StringVar(&listenAddress, "listen-address", "127.0.0.1:3000", "listen address")
+	flag.StringVar(&dockerHost, "docker-host", "unix:///var/run/docker.sock", "docker host")
+	flag.IntVar(&codeHandlingParallelism, "code-handling-parallelism", 4, "code handling parallelism")
+	flag.IntVar(&runHandlingParallelism, "run-handling-parallelism", 30, "run handling parallelism")
+	flag.IntVar(&queueCapacity, "queue-capacity", 10000, "in-memory codes and runs queues capacity")
+	flag.StringVar(&artifactsPath, "artifacts-path", filepath.Join(os.TempDir(), "mycode-artifacts"), "compiled artifacts cache path")
+	flag.DurationVar(&artifactTTL, "artifact-ttl", time.Hour, "compiled artifact cache TTL")
+	flag.StringVar(&blobURI, "blob-uri", "file://"+filepath.Join(os.TempDir(), "mycode-blobs"), "blob storage URI, file:///path or s3://access_key:secret_key@host/bucket")
+	flag.IntVar(&blobThreshold, "blob-threshold", 64<<10, "size in bytes of contents stored in blob storage instead of DB")
+	flag.StringVar(&outputLimit, "output-limit", "64MB", "program stdout and stderr size limit each")
+	flag.StringVar(&storedOutputLimit, "stored-output-limit", "1MB", "stored program stdout and stderr size limit each, larger are truncated")
+	flag.Float64Var(&codesRate, "codes-rate", 0, "codes per second published to runner, 0 disables limiting")
+	flag.IntVar(&codesBurst, "codes-burst", 1000, "codes burst published to runner")
+	flag.Parse()
+
+	switch "" {
+	case postgresURI, jwtSecret, listenAddress, artifactsPath, blobURI:
+		flag.PrintDefaults()
+		return 1
+	}
+
+	var outputLimitBytes, storedOutputLimitBytes datasize.ByteSize
+
+	err := outputLimitBytes.UnmarshalText([]byte(outputLimit))
+	if err != nil {
+		flag.PrintDefaults()
+		return 1
+	}
+
+	err = storedOutputLimitBytes.UnmarshalText([]byte(storedOutputLimit))
+	if err != nil {
+		flag.PrintDefaults()
+		return 1
+	}
+
+	if codeHandlingParallelism <= 0 || runHandlingParallelism <= 0 ||
+		queueCapacity <= 0 || artifactTTL <= 0 || blobThreshold < 0 ||
+		outputLimitBytes == 0 || storedOutputLimitBytes == 0 ||
+		codesRate < 0 || codesBurst <= 0 {
+		flag.PrintDefaults()
+		return 1
+	}
+
+	sig := make(chan os.Signal, 1)
+
+	var stopTime time.Time
+	defer func() {
+		if stopTime.IsZero() {
+			return
+		}
+		logrus.WithField("duration", time.Now().Sub(stopTime)).
+			Info("stopped")
+	}()
+
+	memTransport := mem.NewTransport(queueCapacity)
+
+	logrus.Info("mem_transport created")
+
+	blobStorage, err := blob.Open(blobURI)
+	if err != nil {
+		logrus.WithError(err).Error("failed to open blob_storage")
+		return 2
+	}
+
+	logrus.Info("blob_storage opened")
+
+	pgMyCodeAPI, err := pg.NewMyCodeAPI(postgresURI, jwtSecret,
+		memTransport, blobStorage, blobThreshold,
+		int(storedOutputLimitBytes), codesRate, codesBurst)
+	if err != nil {
+		logrus.WithError(err).Error("failed to create pg_mycode_api")
+		return 3
+	}
+
+	defer func() {
+		err := pgMyCodeAPI.Close()
+		if err != nil {
+			logrus.WithError(err).Error("failed to close pg_mycode_api")
+			return
+		}
+
+		logrus.Info("pg_mycode_api closed")
+	}()
+
+	logrus.Info("pg_mycode_api created")
+
+	err = pgMyCodeAPI.Migrate()
+	if err != nil {
+		logrus.WithError(err).Error("failed to migrate")
+		return 4
+	}
+
+	pgMyCodeAPI.Start()
+
+	logrus.Info("pg_mycode_api started")
+
+	dockerRunner, err := docker.NewRunner(dockerHost, artifactsPath,
+		artifactTTL, memTransport, blobStorage, blobThreshold,
+		outputLimitBytes)
+	if err != nil {
+		logrus.WithError(err).Error("failed to create docker_runner")
+		return 5
+	}
+	defer func() {
+		err := dockerRunner.Close()
+		if err != nil {
+			logrus.WithError(err).Error("failed to close docker_runner")
+			return
+		}
+		logrus.Info("docker_runner closed")
+	}()
+
+	logrus.Info("docker_runner created")
+
+	memCodeConsumer := mem.NewCodeConsumer(memTransport,
+		codeHandlingParallelism, dockerRunner)
+	defer func() {
+		memCodeConsumer.Close()
+		logrus.Info("mem_code_consumer closed")
+	}()
+
+	logrus.Info("mem_code_consumer created and started")
+
+	memRunConsumer := mem.NewRunConsumer(memTransport,
+		runHandlingParallelism, pgMyCodeAPI)
+	defer func() {
+		memRunConsumer.Close()
+		logrus.Info("mem_run_consumer closed")
+	}()
+
+	logrus.Info("mem_run_consumer created and started")
+
+	hooks := &twirp.ServerHooks{}
+	hooks.RequestRouted = func(ctx context.Context) (context.Context, error) {
+
+		method, ok := twirp.MethodName(ctx)
+		if !ok {
+			return ctx, twirp.NewError(twirp.Internal,
+				"missing method name")
+		}
+
+		ctx, err := pgMyCodeAPI.Authorize(ctx, method)
+		if err != nil {
+			return ctx, twirp.NewError(twirp.PermissionDenied,
+				err.Error())
+		}
+
+		return ctx, nil
+	}
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/", mycode.NewAPIServer(pgMyCodeAPI,
+		twirp.WithServerPathPrefix(""),
+		twirp.WithServerHooks(hooks)))
+
+	mux.HandleFunc("/events/solution-tests",
+		pgMyCodeAPI.ServeSolutionTestEvents)
+
+	apiSrv := cors.AllowAll().Handler(pg.WithJWT(mux))
+
+	s := &http.Server{
+		Addr:    listenAddress,
+		Handler: apiSrv,
+	}
+
+	s.RegisterOnShutdown(pgMyCodeAPI.CloseEvents)
+
+	go func() {
+		err := s.ListenAndServe()
+		if err != nil {
+			if err == http.ErrServerClosed {
+				return
+			}
+			logrus.WithError(err).Error("failed to start http_server")
+			sig <- syscall.SIGTERM
+		}
+	}()
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := s.Shutdown(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("failed to stop http_server")
+			return
+		}
+
+		logrus.Info("http_server stopped")
+	}()
+
+	// Transport is closed first, so handlers blocked on publishing to the
+	// full queue don't block consumers closing.
+	defer func() {
+		memTransport.Close()
+		logrus.Info("mem_transport closed")
+	}()
+
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	logrus.Info("started")
+
+	logrus.Infof("catch %s signal, stopping", <-sig)
+
+	stopTime = time.Now()
+
+	return 0
+}
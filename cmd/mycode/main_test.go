@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/twitchtv/twirp"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/blob"
+	"github.com/dimuls/mycode/pg"
+)
+
+const dockerHost = "unix:///var/run/docker.sock"
+
+// TestRun submits solution to the API started by run and waits for it to be
+// judged by docker runner through in-memory transport. Test is skipped
+// without MYCODE_TEST_POSTGRES_URI DB, which schema is dropped, or without
+// docker with mycode-python image.
+func TestRun(t *testing.T) {
+
+	pgURI := os.Getenv("MYCODE_TEST_POSTGRES_URI")
+	if pgURI == "" {
+		t.Skip("MYCODE_TEST_POSTGRES_URI is not set")
+	}
+
+	checkDocker(t)
+
+	classID := prepareDB(t, pgURI)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	listenAddress := l.Addr().String()
+	l.Close()
+
+	os.Args = []string{"mycode",
+		"-postgres-uri", pgURI,
+		"-jwt-secret", "secret",
+		"-listen-address", listenAddress,
+		"-docker-host", dockerHost,
+		"-artifacts-path", t.TempDir(),
+		"-blob-uri", "file://" + t.TempDir(),
+	}
+
+	// Signal is caught before run starts catching it, so stopping run
+	// early doesn't kill the test.
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGTERM)
+
+	exitCode := make(chan int, 1)
+	go func() {
+		exitCode <- run()
+	}()
+
+	defer func() {
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+
+		select {
+		case c := <-exitCode:
+			if c != 0 {
+				t.Errorf("exit code = %d, want 0", c)
+			}
+		case <-time.After(30 * time.Second):
+			t.Error("run not stopped")
+		}
+	}()
+
+	api := mycode.NewAPIProtobufClient("http://"+listenAddress,
+		http.DefaultClient, twirp.WithClientPathPrefix(""))
+
+	teacherCtx := login(t, api, "teacher", true)
+
+	e, err := api.AddExercise(teacherCtx, &mycode.AddExerciseReq{
+		Title:       "A+B",
+		Description: "Add numbers.",
+		Language:    mycode.Language_python,
+	})
+	if err != nil {
+		t.Fatalf("add exercise: %v", err)
+	}
+
+	_, err = api.AddTest(teacherCtx, &mycode.AddTestReq{
+		ExerciseId:     e.ExerciseId,
+		Type:           mycode.TestType_simple,
+		Name:           "1",
+		MaxDuration:    "1s",
+		MaxMemory:      "64MB",
+		Stdin:          "1 2\n",
+		ExpectedStdout: "3\n",
+	})
+	if err != nil {
+		t.Fatalf("add test: %v", err)
+	}
+
+	_, err = api.AssignExercise(teacherCtx, &mycode.AssignExerciseReq{
+		ExerciseId: e.ExerciseId,
+		ClassId:    classID,
+	})
+	if err != nil {
+		t.Fatalf("assign exercise: %v", err)
+	}
+
+	studentCtx := login(t, api, "student", false)
+
+	s, err := api.AddSolution(studentCtx, &mycode.AddSolutionReq{
+		ExerciseId: e.ExerciseId,
+		Source:     "print(sum(map(int, input().split())))",
+	})
+	if err != nil {
+		t.Fatalf("add solution: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Minute)
+
+	for {
+		res, err := api.GetSolutionTests(studentCtx,
+			&mycode.GetSolutionTestsReq{SolutionId: s.SolutionId})
+		if err != nil {
+			t.Fatalf("get solution tests: %v", err)
+		}
+
+		if len(res.SolutionTests) != 1 {
+			t.Fatalf("solution tests = %v, want one", res.SolutionTests)
+		}
+
+		st := res.SolutionTests[0]
+
+		if st.Status != mycode.SolutionTestStatus_processing {
+			if st.Status != mycode.SolutionTestStatus_succeed ||
+				st.Verdict != mycode.Verdict_accepted {
+
+				t.Errorf("solution test = %+v, want accepted", st)
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("solution test not judged")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func checkDocker(t *testing.T) {
+
+	docker, err := client.NewClientWithOpts(client.WithHost(dockerHost),
+		client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("create docker client: %v", err)
+	}
+
+	defer docker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, _, err = docker.ImageInspectWithRaw(ctx, "mycode-python")
+	if err != nil {
+		t.Skipf("inspect mycode-python docker image: %v", err)
+	}
+}
+
+// prepareDB migrates clean DB and adds teacher and student users with
+// their login as password. Returns student class ID.
+func prepareDB(t *testing.T, pgURI string) int64 {
+
+	db, err := sql.Open("postgres", pgURI)
+	if err != nil {
+		t.Fatalf("open DB: %v", err)
+	}
+
+	defer db.Close()
+
+	_, err = db.Exec(`drop schema public cascade; create schema public`)
+	if err != nil {
+		t.Fatalf("drop DB schema: %v", err)
+	}
+
+	bs, err := blob.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob storage: %v", err)
+	}
+
+	api, err := pg.NewMyCodeAPI(pgURI, "secret", nil, bs, 0, 1, 0, 1)
+	if err != nil {
+		t.Fatalf("create API: %v", err)
+	}
+
+	err = api.Migrate()
+	api.Close()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	teacherHash, err := bcrypt.GenerateFromPassword([]byte("teacher"),
+		bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	studentHash, err := bcrypt.GenerateFromPassword([]byte("student"),
+		bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	var classID int64
+
+	err = db.QueryRow(`
+		with u as (
+			insert into "user" (login, password_hash)
+			values ('teacher', $1) returning id
+		), t as (
+			insert into teacher (user_id, name)
+			select id, 'Teacher' from u returning id
+		)
+		insert into class (teacher_id, name)
+		select id, '1A' from t returning id
+	`, teacherHash).Scan(&classID)
+	if err != nil {
+		t.Fatalf("add teacher to DB: %v", err)
+	}
+
+	_, err = db.Exec(`
+		with u as (
+			insert into "user" (login, password_hash)
+			values ('student', $1) returning id
+		)
+		insert into student (user_id, class_id, name)
+		select id, $2, 'Student' from u
+	`, studentHash, classID)
+	if err != nil {
+		t.Fatalf("add student to DB: %v", err)
+	}
+
+	return classID
+}
+
+// login logs user in, waiting for API to start, and returns context with
+// user's JWT.
+func login(t *testing.T, api mycode.API, user string,
+	teacher bool) context.Context {
+
+	deadline := time.Now().Add(time.Minute)
+
+	for {
+		res, err := api.Login(context.Background(), &mycode.LoginReq{
+			Login:    user,
+			Password: user,
+		})
+		if err == nil {
+			if teacher != (res.Teacher != nil) {
+				t.Fatalf("login %s = %+v, want teacher %v", user, res,
+					teacher)
+			}
+
+			ctx, err := twirp.WithHTTPRequestHeaders(context.Background(),
+				http.Header{"Authorization": {res.Jwt}})
+			if err != nil {
+				t.Fatalf("set JWT header: %v", err)
+			}
+
+			return ctx
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("login %s: %v", user, err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
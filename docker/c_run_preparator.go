@@ -14,6 +14,12 @@ var (
 	cRunCmd     = []string{"./a.out"}
 )
 
+var cRun = simpleRun{
+	sourcePath:     cSrcPath,
+	compileCommand: cCompileCmd,
+	runCommand:     cRunCmd,
+}
+
 type CRunPreparator struct{}
 
 func (p CRunPreparator) Prepare(source string) (Run, error) {
@@ -23,11 +29,17 @@ func (p CRunPreparator) Prepare(source string) (Run, error) {
 		return nil, err
 	}
 
-	return simpleRun{
-		sourcePath:     cSrcPath,
-		compileCommand: cCompileCmd,
-		runCommand:     cRunCmd,
-	}, nil
+	return cRun, nil
+}
+
+func (p CRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(cSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return cRun, nil
 }
 
 func init() {
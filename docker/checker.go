@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+// Testlib checker is run with input, participant output and expected
+// answer file paths as arguments. Verdict is defined by exit code, message
+// is written to stderr.
+const (
+	checkerExitOK                = 0
+	checkerExitWrongAnswer       = 1
+	checkerExitPresentationError = 2
+	checkerExitFail              = 3
+	checkerExitPartially         = 7
+)
+
+// Stdin ok checker gets participant output on stdin and accepts it by
+// printing stdinOKCheckerAccepted.
+const stdinOKCheckerAccepted = "ok"
+
+// checkStdinOKResult gets checker verdict, score from 0 to 1 and message
+// from the stdin ok checker run.
+func checkStdinOKResult(r *mycode.Run) (mycode.CheckerVerdict, float64,
+	string) {
+
+	msg := strings.TrimSpace(r.Stderr)
+
+	switch r.Verdict {
+	case mycode.Verdict_accepted, mycode.Verdict_runtime_error:
+	default:
+		return mycode.CheckerVerdict_checker_fail, 0, msg
+	}
+
+	if r.Stdout != stdinOKCheckerAccepted {
+		return mycode.CheckerVerdict_checker_wrong_answer, 0, msg
+	}
+
+	return mycode.CheckerVerdict_checker_ok, 1, msg
+}
+
+// checkResult gets testlib checker verdict, score from 0 to 1 and message from the
+// checker run. Partial score is the first number of the message, optionally
+// prefixed with "points".
+func checkResult(r *mycode.Run) (mycode.CheckerVerdict, float64, string) {
+
+	msg := strings.TrimSpace(r.Stderr)
+
+	switch r.Verdict {
+	case mycode.Verdict_accepted, mycode.Verdict_runtime_error:
+	default:
+		return mycode.CheckerVerdict_checker_fail, 0, msg
+	}
+
+	if r.Signal != 0 {
+		return mycode.CheckerVerdict_checker_fail, 0, msg
+	}
+
+	switch r.ExitCode {
+	case checkerExitOK:
+		return mycode.CheckerVerdict_checker_ok, 1, msg
+	case checkerExitWrongAnswer:
+		return mycode.CheckerVerdict_checker_wrong_answer, 0, msg
+	case checkerExitPresentationError:
+		return mycode.CheckerVerdict_checker_presentation_error, 0, msg
+	case checkerExitPartially:
+		fs := strings.Fields(msg)
+		if len(fs) != 0 && fs[0] == "points" {
+			fs = fs[1:]
+		}
+		if len(fs) == 0 {
+			return mycode.CheckerVerdict_checker_fail, 0, msg
+		}
+		score, err := strconv.ParseFloat(fs[0], 64)
+		if err != nil || math.IsNaN(score) {
+			return mycode.CheckerVerdict_checker_fail, 0, msg
+		}
+		return mycode.CheckerVerdict_checker_partially,
+			math.Max(0, math.Min(1, score)), msg
+	default:
+		return mycode.CheckerVerdict_checker_fail, 0, msg
+	}
+}
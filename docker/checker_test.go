@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestCheckResult(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		run         *mycode.Run
+		wantVerdict mycode.CheckerVerdict
+		wantScore   float64
+		wantMessage string
+	}{{
+		name: "ok",
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			ExitCode: checkerExitOK, Stderr: "ok 3 numbers\n"},
+		wantVerdict: mycode.CheckerVerdict_checker_ok,
+		wantScore:   1,
+		wantMessage: "ok 3 numbers",
+	}, {
+		name: "wrong answer",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitWrongAnswer, Stderr: "wrong answer 1st"},
+		wantVerdict: mycode.CheckerVerdict_checker_wrong_answer,
+		wantMessage: "wrong answer 1st",
+	}, {
+		name: "presentation error",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPresentationError},
+		wantVerdict: mycode.CheckerVerdict_checker_presentation_error,
+	}, {
+		name: "fail",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitFail, Stderr: "FAIL bad input"},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+		wantMessage: "FAIL bad input",
+	}, {
+		name: "partially",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "0.25 one of four\n"},
+		wantVerdict: mycode.CheckerVerdict_checker_partially,
+		wantScore:   0.25,
+		wantMessage: "0.25 one of four",
+	}, {
+		name: "partially with points prefix",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "points 0.5"},
+		wantVerdict: mycode.CheckerVerdict_checker_partially,
+		wantScore:   0.5,
+		wantMessage: "points 0.5",
+	}, {
+		name: "partially above one",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "points 1.5"},
+		wantVerdict: mycode.CheckerVerdict_checker_partially,
+		wantScore:   1,
+		wantMessage: "points 1.5",
+	}, {
+		name: "partially below zero",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "-0.5"},
+		wantVerdict: mycode.CheckerVerdict_checker_partially,
+		wantScore:   0,
+		wantMessage: "-0.5",
+	}, {
+		name: "partially without points",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "points"},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+		wantMessage: "points",
+	}, {
+		name: "partially with empty message",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+	}, {
+		name: "partially with non-numeric points",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "half"},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+		wantMessage: "half",
+	}, {
+		name: "partially with NaN points",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: checkerExitPartially, Stderr: "points NaN"},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+		wantMessage: "points NaN",
+	}, {
+		name: "unexpected exit code",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			ExitCode: 4},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+	}, {
+		name: "killed by signal",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Signal: 11},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+	}, {
+		name: "time limit exceeded",
+		run: &mycode.Run{Verdict: mycode.Verdict_time_limit_exceeded,
+			ExitCode: checkerExitOK},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			verdict, score, msg := checkResult(tt.run)
+
+			if verdict != tt.wantVerdict {
+				t.Errorf("verdict = %v, want %v", verdict, tt.wantVerdict)
+			}
+
+			if score != tt.wantScore {
+				t.Errorf("score = %v, want %v", score, tt.wantScore)
+			}
+
+			if msg != tt.wantMessage {
+				t.Errorf("message = %q, want %q", msg, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestCheckStdinOKResult(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		run         *mycode.Run
+		wantVerdict mycode.CheckerVerdict
+		wantScore   float64
+	}{{
+		name: "ok",
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Stdout: stdinOKCheckerAccepted},
+		wantVerdict: mycode.CheckerVerdict_checker_ok,
+		wantScore:   1,
+	}, {
+		name: "ok with runtime error",
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Stdout: stdinOKCheckerAccepted, ExitCode: 1},
+		wantVerdict: mycode.CheckerVerdict_checker_ok,
+		wantScore:   1,
+	}, {
+		name: "ok with newline",
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Stdout: stdinOKCheckerAccepted + "\n"},
+		wantVerdict: mycode.CheckerVerdict_checker_wrong_answer,
+	}, {
+		name:        "wrong answer",
+		run:         &mycode.Run{Verdict: mycode.Verdict_accepted},
+		wantVerdict: mycode.CheckerVerdict_checker_wrong_answer,
+	}, {
+		name: "memory limit exceeded",
+		run: &mycode.Run{Verdict: mycode.Verdict_memory_limit_exceeded,
+			Stdout: stdinOKCheckerAccepted},
+		wantVerdict: mycode.CheckerVerdict_checker_fail,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			verdict, score, _ := checkStdinOKResult(tt.run)
+
+			if verdict != tt.wantVerdict {
+				t.Errorf("verdict = %v, want %v", verdict, tt.wantVerdict)
+			}
+
+			if score != tt.wantScore {
+				t.Errorf("score = %v, want %v", score, tt.wantScore)
+			}
+		})
+	}
+}
@@ -12,6 +12,12 @@ var (
 	cppRunCmd     = []string{"./a.out"}
 )
 
+var cppRun = simpleRun{
+	sourcePath:     cppSrcPath,
+	compileCommand: cppCompileCmd,
+	runCommand:     cppRunCmd,
+}
+
 type CPPRunPreparator struct{}
 
 func (p CPPRunPreparator) Prepare(source string) (Run, error) {
@@ -21,11 +27,17 @@ func (p CPPRunPreparator) Prepare(source string) (Run, error) {
 		return nil, err
 	}
 
-	return simpleRun{
-		sourcePath:     cppSrcPath,
-		compileCommand: cppCompileCmd,
-		runCommand:     cppRunCmd,
-	}, nil
+	return cppRun, nil
+}
+
+func (p CPPRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(cppSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return cppRun, nil
 }
 
 func init() {
@@ -12,6 +12,12 @@ var (
 	goRunCmd     = []string{"./main"}
 )
 
+var goRun = simpleRun{
+	sourcePath:     goSrcPath,
+	compileCommand: goCompileCmd,
+	runCommand:     goRunCmd,
+}
+
 type GoRunPreparator struct{}
 
 func (p GoRunPreparator) Prepare(source string) (Run, error) {
@@ -21,11 +27,17 @@ func (p GoRunPreparator) Prepare(source string) (Run, error) {
 		return nil, err
 	}
 
-	return simpleRun{
-		sourcePath:     goSrcPath,
-		compileCommand: goCompileCmd,
-		runCommand:     goRunCmd,
-	}, nil
+	return goRun, nil
+}
+
+func (p GoRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(goSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return goRun, nil
 }
 
 func init() {
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	toSolutionPipe   = "to_solution"
+	fromSolutionPipe = "from_solution"
+)
+
+// interact runs solution together with the interactor. Solution stdin and
+// stdout are cross-wired with the interactor ones through FIFOs. Interactor
+// gets test input and answer file paths as arguments and reports verdict
+// with the checker exit code.
+func (r *Runner) interact(ctx context.Context, log *logrus.Entry,
+	solution *artifact, c *mycode.Code) (*mycode.Run, error) {
+
+	solutionLog := log.WithField("code_type", "solution")
+	interactorLog := log.WithField("code_type", "interactor")
+
+	if solution.compilation.Failed {
+		return &mycode.Run{
+			Verdict:        mycode.Verdict_compilation_error,
+			CompilerOutput: compilerOutput(solution.compilation),
+		}, nil
+	}
+
+	interactor, release, err := r.compile(ctx, interactorLog,
+		c.CheckerLanguage, c.CheckerSource)
+	if err != nil {
+		return nil, fmt.Errorf("compile interactor code: %w", err)
+	}
+
+	defer release()
+
+	if interactor.compilation.Failed {
+		return &mycode.Run{
+			Verdict:       mycode.Verdict_internal_error,
+			CheckerStderr: compilerOutput(interactor.compilation),
+		}, nil
+	}
+
+	pipesPath, err := createPipes()
+	defer func() {
+		err := os.RemoveAll(pipesPath)
+		if err != nil {
+			log.WithError(err).WithField("pipes_path", pipesPath).
+				Error("failed to remove pipes")
+		}
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("create pipes: %w", err)
+	}
+
+	interactorMaxDuration := checkerMaxDuration
+
+	timeLimit, err := time.ParseDuration(c.MaxDuration)
+	if err != nil {
+		return nil, fmt.Errorf("parse max duration: %w", err)
+	}
+
+	checkerTimeLimit, err := time.ParseDuration(checkerMaxDuration)
+	if err != nil {
+		return nil, fmt.Errorf("parse checker max duration: %w", err)
+	}
+
+	// Interactor waits for solution, so it can't have less time.
+	if timeLimit > checkerTimeLimit {
+		interactorMaxDuration = c.MaxDuration
+	}
+
+	// Program, which failed before opening its pipes, leaves another one
+	// blocked, so both runs are canceled on the first error.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg                         sync.WaitGroup
+		solutionRun, interactorRun *mycode.Run
+		solutionErr, interactorErr error
+	)
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		solutionRun, solutionErr = r.run(ctx, solutionLog, solution,
+			runParams{
+				maxDuration: c.MaxDuration,
+				maxMemory:   c.MaxMemory,
+				pipesPath:   pipesPath,
+				stdinPipe:   toSolutionPipe,
+				stdoutPipe:  fromSolutionPipe,
+			})
+		if solutionErr != nil {
+			cancel()
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		interactorRun, interactorErr = r.run(ctx, interactorLog, interactor,
+			runParams{
+				maxDuration: interactorMaxDuration,
+				maxMemory:   checkerMaxMemory,
+				argFiles: []runFile{
+					{name: "input", content: c.Stdin},
+					{name: "answer", content: c.ExpectedStdout},
+				},
+				pipesPath:   pipesPath,
+				stdinPipe:   fromSolutionPipe,
+				stdoutPipe:  toSolutionPipe,
+				stdoutFirst: true,
+			})
+		if interactorErr != nil {
+			cancel()
+		}
+	}()
+
+	wg.Wait()
+
+	if solutionErr != nil {
+		return nil, fmt.Errorf("run solution code: %w", solutionErr)
+	}
+
+	if interactorErr != nil {
+		return nil, fmt.Errorf("run interactor code: %w", interactorErr)
+	}
+
+	solutionRun.CheckerStdout = interactorRun.Stdout
+	solutionRun.CheckerStderr = interactorRun.Stderr
+
+	switch {
+	case interactorRun.Verdict == mycode.Verdict_internal_error:
+		solutionRun.Verdict = mycode.Verdict_internal_error
+	case solutionRun.Verdict == mycode.Verdict_accepted:
+		solutionRun.CheckerVerdict, solutionRun.Score,
+			solutionRun.CheckerMessage = checkResult(interactorRun)
+	}
+
+	return solutionRun, nil
+}
+
+// createPipes creates directory with FIFOs for solution and interactor
+// communication.
+func createPipes() (string, error) {
+
+	path, err := ioutil.TempDir("", "pipes-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+
+	for _, name := range []string{toSolutionPipe, fromSolutionPipe} {
+		err = syscall.Mkfifo(filepath.Join(path, name), 0600)
+		if err != nil {
+			return path, fmt.Errorf("make %s FIFO: %w", name, err)
+		}
+	}
+
+	return path, nil
+}
@@ -67,6 +67,16 @@ func (p JavaRunPreparator) Prepare(source string) (Run, error) {
 	return javaRun{}, nil
 }
 
+func (p JavaRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(javaSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return javaRun{}, nil
+}
+
 func init() {
 	RegisterRunPreparator(mycode.Language_java, JavaRunPreparator{})
 }
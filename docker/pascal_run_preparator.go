@@ -12,6 +12,12 @@ var (
 	pascalRunCmd     = []string{"./main"}
 )
 
+var pascalRun = simpleRun{
+	sourcePath:     pascalSrcPath,
+	compileCommand: pascalCompileCmd,
+	runCommand:     pascalRunCmd,
+}
+
 type PascalRunPreparator struct{}
 
 func (p PascalRunPreparator) Prepare(source string) (Run, error) {
@@ -21,11 +27,17 @@ func (p PascalRunPreparator) Prepare(source string) (Run, error) {
 		return nil, err
 	}
 
-	return simpleRun{
-		sourcePath:     pascalSrcPath,
-		compileCommand: pascalCompileCmd,
-		runCommand:     pascalRunCmd,
-	}, nil
+	return pascalRun, nil
+}
+
+func (p PascalRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(pascalSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return pascalRun, nil
 }
 
 func init() {
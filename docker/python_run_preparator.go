@@ -9,6 +9,12 @@ const (
 
 var pythonRunCmd = []string{"python3", pythonSrcFileName}
 
+var pythonRun = simpleRun{
+	sourcePath:      pythonSrcPath,
+	additionalBinds: []string{"/usr/bin/python3"},
+	runCommand:      pythonRunCmd,
+}
+
 type PythonRunPreparator struct{}
 
 func (p PythonRunPreparator) Prepare(source string) (Run, error) {
@@ -18,11 +24,17 @@ func (p PythonRunPreparator) Prepare(source string) (Run, error) {
 		return nil, err
 	}
 
-	return simpleRun{
-		sourcePath:      pythonSrcPath,
-		additionalBinds: []string{"/usr/bin/python3"},
-		runCommand:      pythonRunCmd,
-	}, nil
+	return pythonRun, nil
+}
+
+func (p PythonRunPreparator) Restore(artifactPath string) (Run, error) {
+
+	err := restoreSrcPath(pythonSrcPath, artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return pythonRun, nil
 }
 
 func init() {
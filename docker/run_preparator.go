@@ -2,6 +2,7 @@ package docker
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -18,6 +19,7 @@ type Run interface {
 
 type RunPreparator interface {
 	Prepare(source string) (Run, error)
+	Restore(artifactPath string) (Run, error)
 }
 
 var preparators = map[mycode.Language]RunPreparator{}
@@ -42,6 +44,39 @@ func Prepare(language mycode.Language, source string) (
 	return r, nil
 }
 
+// Restore prepares run from the artifact previously saved by Save, so
+// compiled code can be run without compilation.
+func Restore(language mycode.Language, artifactPath string) (Run, error) {
+
+	p, exists := preparators[language]
+	if !exists {
+		return nil, fmt.Errorf("unknown language")
+	}
+
+	r, err := p.Restore(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("restore code: %w", err)
+	}
+
+	return r, nil
+}
+
+// Save copies prepared and compiled run source path to the artifact path.
+func Save(r Run, artifactPath string) error {
+
+	srcPath, err := r.SourcePath()
+	if err != nil {
+		return fmt.Errorf("get source path: %w", err)
+	}
+
+	err = copyDir(artifactPath, srcPath)
+	if err != nil {
+		return fmt.Errorf("copy source path to artifact path: %w", err)
+	}
+
+	return nil
+}
+
 func makeSrcPath(srcPath, srcFileName, src string) error {
 	err := os.MkdirAll(srcPath, 0755)
 	if err != nil {
@@ -57,6 +92,68 @@ func makeSrcPath(srcPath, srcFileName, src string) error {
 	return nil
 }
 
+func restoreSrcPath(srcPath, artifactPath string) error {
+	err := os.MkdirAll(srcPath, 0755)
+	if err != nil {
+		return fmt.Errorf("make soruce path directory: %w", err)
+	}
+
+	err = copyDir(srcPath, artifactPath)
+	if err != nil {
+		return fmt.Errorf("copy artifact to source path: %w", err)
+	}
+
+	return nil
+}
+
+func copyDir(dst, src string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo,
+		err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFile(target, path, info.Mode().Perm())
+	})
+}
+
+func copyFile(dst, src string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
 type simpleRun struct {
 	sourcePath      string
 	additionalBinds []string
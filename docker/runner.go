@@ -3,13 +3,16 @@ package docker
 import (
 	"bytes"
 	"context"
-	"flag"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
@@ -19,6 +22,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/blob"
 )
 
 type RunPublisher interface {
@@ -26,15 +30,33 @@ type RunPublisher interface {
 }
 
 type Runner struct {
-	docker       *client.Client
-	runPublisher RunPublisher
-
-	log *logrus.Entry
-	wg  sync.WaitGroup
+	docker        *client.Client
+	runPublisher  RunPublisher
+	artifactsPath string
+	artifactTTL   time.Duration
+	blobs         blob.Storage
+	blobThreshold int
+	outputLimit   datasize.ByteSize
+
+	artifactLocksMx sync.Mutex
+	artifactLocks   map[string]*artifactLock
+
+	log  *logrus.Entry
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
-func NewRunner(dockerHost string, rp RunPublisher) (
-	r *Runner, err error) {
+// NewRunner creates runner. Code stdins and expected stdouts are fetched
+// from the blob storage by reference. Runs stdouts and stderrs larger than
+// blobThreshold bytes are stored there. Programs which stdout or stderr
+// exceed outputLimit are killed.
+func NewRunner(dockerHost, artifactsPath string, artifactTTL time.Duration,
+	rp RunPublisher, bs blob.Storage, blobThreshold int,
+	outputLimit datasize.ByteSize) (r *Runner, err error) {
+
+	if outputLimit == 0 {
+		return nil, fmt.Errorf("zero output limit")
+	}
 
 	docker, err := client.NewClientWithOpts(client.WithHost(dockerHost),
 		client.WithAPIVersionNegotiation())
@@ -42,14 +64,37 @@ func NewRunner(dockerHost string, rp RunPublisher) (
 		return nil, fmt.Errorf("create docker client: %w", err)
 	}
 
-	return &Runner{
-		docker:       docker,
-		runPublisher: rp,
-		log:          logrus.WithField("subsystem", "nats_runner"),
-	}, nil
+	err = os.MkdirAll(artifactsPath, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("make artifacts directory: %w", err)
+	}
+
+	r = &Runner{
+		docker:        docker,
+		runPublisher:  rp,
+		artifactsPath: artifactsPath,
+		artifactTTL:   artifactTTL,
+		blobs:         bs,
+		blobThreshold: blobThreshold,
+		outputLimit:   outputLimit,
+		artifactLocks: map[string]*artifactLock{},
+		log:           logrus.WithField("subsystem", "nats_runner"),
+		stop:          make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.cleanArtifacts()
+	}()
+
+	return r, nil
 }
 
 func (r *Runner) Close() error {
+	close(r.stop)
+	r.wg.Wait()
+
 	err := r.docker.Close()
 	if err != nil {
 		return fmt.Errorf("close docker client: %w", err)
@@ -57,10 +102,31 @@ func (r *Runner) Close() error {
 	return nil
 }
 
+// WallTimeLimitFactor is wall time limit to CPU time limit ratio. Wall time
+// limit stops programs which sleep or block instead of consuming CPU.
+const WallTimeLimitFactor = 3
+
 const (
 	myCodeRun = "mycode-run"
 
-	containerRunTimeout = 60 * time.Second
+	artifactContainerPath = "/artifact"
+	filesContainerPath    = "/files"
+	stdinContainerPath    = "/stdin"
+	pipesContainerPath    = "/pipes"
+	artifactDirName       = "artifact"
+	compilationFileName   = "compilation.json"
+	stdinFileName         = "stdin"
+
+	artifactsCleanPeriod = 10 * time.Minute
+
+	containerCompileTimeout = 60 * time.Second
+
+	// containerRunOverhead is container run timeout addition to wall time
+	// limit for container start, sandbox setup and output collection.
+	containerRunOverhead = 30 * time.Second
+
+	checkerMaxDuration = "10s"
+	checkerMaxMemory   = "256MB"
 )
 
 var images = map[mycode.Language]string{
@@ -72,8 +138,286 @@ var images = map[mycode.Language]string{
 	mycode.Language_python: "mycode-python",
 }
 
+type artifactLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// lockArtifact locks artifact exclusively for compilation or cleaning.
+func (r *Runner) lockArtifact(key string) (unlock func()) {
+	l := r.acquireArtifactLock(key)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		r.releaseArtifactLock(key)
+	}
+}
+
+// rLockArtifact locks artifact for run, so it can't be cleaned while used.
+func (r *Runner) rLockArtifact(key string) (rUnlock func()) {
+	l := r.acquireArtifactLock(key)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		r.releaseArtifactLock(key)
+	}
+}
+
+func (r *Runner) acquireArtifactLock(key string) *artifactLock {
+	r.artifactLocksMx.Lock()
+	defer r.artifactLocksMx.Unlock()
+
+	l, exists := r.artifactLocks[key]
+	if !exists {
+		l = &artifactLock{}
+		r.artifactLocks[key] = l
+	}
+
+	l.refs++
+
+	return l
+}
+
+func (r *Runner) releaseArtifactLock(key string) {
+	r.artifactLocksMx.Lock()
+	defer r.artifactLocksMx.Unlock()
+
+	l := r.artifactLocks[key]
+
+	l.refs--
+
+	if l.refs == 0 {
+		delete(r.artifactLocks, key)
+	}
+}
+
+func artifactKey(lang mycode.Language, source string) string {
+	h := sha256.New()
+	h.Write([]byte(lang.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type artifact struct {
+	key         string
+	language    mycode.Language
+	path        string
+	compilation *mycode.Compilation
+}
+
+func (r *Runner) loadArtifact(key string) (*mycode.Compilation, error) {
+
+	keyPath := filepath.Join(r.artifactsPath, key)
+
+	f, err := os.Open(filepath.Join(keyPath, compilationFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &mycode.Compilation{}
+
+	err = jsonpb.Unmarshal(f, c)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal compilation: %w", err)
+	}
+
+	now := time.Now()
+
+	err = os.Chtimes(keyPath, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("touch artifact: %w", err)
+	}
+
+	return c, nil
+}
+
+// compile compiles source once and caches the result by source hash.
+// Returned artifact is locked for run until release is called.
+func (r *Runner) compile(ctx context.Context, log *logrus.Entry,
+	lang mycode.Language, source string) (a *artifact, release func(),
+	err error) {
+
+	key := artifactKey(lang, source)
+
+	log = log.WithField("artifact", key)
+
+	a = &artifact{
+		key:      key,
+		language: lang,
+		path:     filepath.Join(r.artifactsPath, key, artifactDirName),
+	}
+
+	unlock := r.lockArtifact(key)
+
+	a.compilation, err = r.loadArtifact(key)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Warn("failed to load artifact, recompiling")
+		}
+		a.compilation, err = r.compileArtifact(ctx, log, a, source)
+	} else {
+		log.Debug("artifact found")
+	}
+
+	unlock()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return a, r.rLockArtifact(key), nil
+}
+
+func (r *Runner) compileArtifact(ctx context.Context, log *logrus.Entry,
+	a *artifact, source string) (*mycode.Compilation, error) {
+
+	image, exists := images[a.language]
+	if !exists {
+		return nil, fmt.Errorf("unsupported language")
+	}
+
+	keyPath := filepath.Join(r.artifactsPath, a.key)
+
+	err := os.RemoveAll(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("remove broken artifact: %w", err)
+	}
+
+	err = os.MkdirAll(a.path, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("make artifact directory: %w", err)
+	}
+
+	srcPath, err := r.createSrcFile(a.language, source)
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer func() {
+		err := r.removeSrcFile(srcPath)
+		if err != nil {
+			log.WithError(err).WithField("src_path", srcPath).
+				Error("failed to remove src file")
+		}
+	}()
+
+	stdOut, err := r.runContainer(ctx, log, image,
+		containerCompileTimeout, []string{
+			myCodeRun,
+			"-mode", "compile",
+			"-language", a.language.String(),
+			"-source", srcPath,
+			"-artifact", artifactContainerPath,
+		}, []mount.Mount{
+			{
+				Type:   mount.TypeBind,
+				Source: srcPath,
+				Target: srcPath,
+			},
+			{
+				Type:   mount.TypeBind,
+				Source: a.path,
+				Target: artifactContainerPath,
+			},
+		})
+	if err != nil {
+		return nil, fmt.Errorf("run compile container: %w", err)
+	}
+
+	c := &mycode.Compilation{}
+
+	err = jsonpb.Unmarshal(stdOut, c)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal compilation: %w", err)
+	}
+
+	// Compilation file is written last, so its presence means that
+	// artifact is complete.
+	compilationJSON, err := (&jsonpb.Marshaler{}).MarshalToString(c)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshal compilation: %w", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(keyPath, compilationFileName),
+		[]byte(compilationJSON), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("write compilation: %w", err)
+	}
+
+	log.WithField("failed", c.Failed).Info("code compiled")
+
+	return c, nil
+}
+
+func (r *Runner) cleanArtifacts() {
+	t := time.NewTicker(artifactsCleanPeriod)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+		}
+
+		dir, err := ioutil.ReadDir(r.artifactsPath)
+		if err != nil {
+			r.log.WithError(err).Error("failed to read artifacts directory")
+			continue
+		}
+
+		for _, f := range dir {
+			if !f.IsDir() || time.Since(f.ModTime()) < r.artifactTTL {
+				continue
+			}
+			r.removeArtifact(f.Name())
+		}
+	}
+}
+
+func (r *Runner) removeArtifact(key string) {
+	unlock := r.lockArtifact(key)
+	defer unlock()
+
+	keyPath := filepath.Join(r.artifactsPath, key)
+
+	fi, err := os.Stat(keyPath)
+	if err != nil || time.Since(fi.ModTime()) < r.artifactTTL {
+		return
+	}
+
+	err = os.RemoveAll(keyPath)
+	if err != nil {
+		r.log.WithError(err).WithField("artifact", key).
+			Error("failed to remove artifact")
+	}
+}
+
+// runFile is file written by runner for the program run, like stdin or
+// file passed to the program as argument.
+type runFile struct {
+	name    string
+	content string
+}
+
+type runParams struct {
+	stdin       string
+	maxDuration string
+	maxMemory   string
+	argFiles    []runFile
+
+	// pipesPath is directory with FIFOs, which are used as program stdin
+	// and stdout instead of the stdin string.
+	pipesPath   string
+	stdinPipe   string
+	stdoutPipe  string
+	stdoutFirst bool
+}
+
 func (r *Runner) run(ctx context.Context, log *logrus.Entry,
-	c *mycode.Code) (run *mycode.Run, err error) {
+	a *artifact, p runParams) (run *mycode.Run, err error) {
 
 	defer func() {
 		if err != nil {
@@ -83,45 +427,139 @@ func (r *Runner) run(ctx context.Context, log *logrus.Entry,
 		}
 	}()
 
-	image, exists := images[c.Language]
+	if a.compilation.Failed {
+		return &mycode.Run{
+			Verdict:        mycode.Verdict_compilation_error,
+			CompilerOutput: compilerOutput(a.compilation),
+		}, nil
+	}
+
+	image, exists := images[a.language]
 	if !exists {
 		return nil, fmt.Errorf("unsupported language")
 	}
 
-	srcPath, err := r.createSrcFile(c.Language, c.Source)
+	timeLimit, err := time.ParseDuration(p.maxDuration)
 	if err != nil {
-		return nil, fmt.Errorf("create temp file: %w", err)
+		return nil, fmt.Errorf("parse max duration: %w", err)
 	}
 
-	defer func() {
-		err := r.removeSrcFile(srcPath)
+	cmd := []string{
+		myCodeRun,
+		"-mode", "run",
+		"-language", a.language.String(),
+		"-artifact", artifactContainerPath,
+		"-time-limit", p.maxDuration,
+		"-memory-limit", p.maxMemory,
+		"-output-limit", r.outputLimit.String(),
+	}
+
+	mounts := []mount.Mount{
+		{
+			Type:     mount.TypeBind,
+			Source:   a.path,
+			Target:   artifactContainerPath,
+			ReadOnly: true,
+		},
+	}
+
+	if p.pipesPath != "" {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: p.pipesPath,
+			Target: pipesContainerPath,
+		})
+
+		cmd = append(cmd,
+			"-stdin-path", filepath.Join(pipesContainerPath, p.stdinPipe),
+			"-stdout-path", filepath.Join(pipesContainerPath, p.stdoutPipe))
+
+		if p.stdoutFirst {
+			cmd = append(cmd, "-open-stdout-first")
+		}
+	} else if p.stdin != "" {
+		// Stdin is passed as file, since it can be larger than the
+		// arguments size limit and contain NUL bytes.
+		stdinPath, err := r.createFiles([]runFile{
+			{name: stdinFileName, content: p.stdin},
+		})
+		defer func() {
+			err := os.RemoveAll(stdinPath)
+			if err != nil {
+				log.WithError(err).WithField("stdin_path", stdinPath).
+					Error("failed to remove stdin file")
+			}
+		}()
 		if err != nil {
-			log.WithError(err).WithField("src_path", srcPath).
-				Error("failed to remove src file")
+			return nil, fmt.Errorf("create stdin file: %w", err)
 		}
-	}()
 
-	flag.Parse()
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   stdinPath,
+			Target:   stdinContainerPath,
+			ReadOnly: true,
+		})
+
+		cmd = append(cmd, "-stdin-path",
+			filepath.Join(stdinContainerPath, stdinFileName))
+	}
+
+	if len(p.argFiles) != 0 {
+		filesPath, err := r.createFiles(p.argFiles)
+		defer func() {
+			err := os.RemoveAll(filesPath)
+			if err != nil {
+				log.WithError(err).WithField("files_path", filesPath).
+					Error("failed to remove arg files")
+			}
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("create arg files: %w", err)
+		}
+
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   filesPath,
+			Target:   filesContainerPath,
+			ReadOnly: true,
+		})
+
+		cmd = append(cmd, "-files", filesContainerPath, "--")
+
+		for _, f := range p.argFiles {
+			cmd = append(cmd, filepath.Join(filesContainerPath, f.name))
+		}
+	}
+
+	stdOut, err := r.runContainer(ctx, log, image,
+		timeLimit*WallTimeLimitFactor+containerRunOverhead, cmd, mounts)
+	if err != nil {
+		return nil, fmt.Errorf("run container: %w", err)
+	}
+
+	run = &mycode.Run{}
+
+	err = jsonpb.Unmarshal(stdOut, run)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal run: %w", err)
+	}
+
+	return run, nil
+}
+
+func (r *Runner) runContainer(ctx context.Context, log *logrus.Entry,
+	image string, timeout time.Duration, cmd []string,
+	mounts []mount.Mount) (*bytes.Buffer, error) {
 
 	createResp, err := r.docker.ContainerCreate(ctx,
 		&container.Config{
-			Image: image,
-			Cmd: []string{
-				myCodeRun,
-				c.Language.String(),
-				srcPath,
-				c.Stdin,
-			},
+			Image:           image,
+			Cmd:             cmd,
 			NetworkDisabled: true,
 		}, &container.HostConfig{
 			Privileged: true,
-			Mounts: []mount.Mount{
-				{
-					Type:   mount.TypeBind,
-					Source: srcPath,
-					Target: srcPath,
-				},
-			},
+			Mounts:     mounts,
 		}, nil, "")
 	if err != nil {
 		return nil, fmt.Errorf("create container: %w", err)
@@ -144,8 +582,7 @@ func (r *Runner) run(ctx context.Context, log *logrus.Entry,
 		return nil, fmt.Errorf("start container: %w", err)
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(ctx,
-		containerRunTimeout)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	waitRespChan, errChan := r.docker.ContainerWait(ctxWithTimeout,
@@ -193,15 +630,7 @@ func (r *Runner) run(ctx context.Context, log *logrus.Entry,
 		return nil, fmt.Errorf("not zero container exit code")
 	}
 
-	run = &mycode.Run{}
-
-	err = jsonpb.Unmarshal(&stdOut, run)
-	if err != nil {
-		fmt.Println(run)
-		return nil, fmt.Errorf("JSON unmarshal run: %w", err)
-	}
-
-	return run, nil
+	return &stdOut, nil
 }
 
 func (r *Runner) HandleCode(ctx context.Context, c *mycode.Code) (err error) {
@@ -212,25 +641,71 @@ func (r *Runner) HandleCode(ctx context.Context, c *mycode.Code) (err error) {
 
 	log.Info("code recieved")
 
+	err = r.getBlobs(ctx, c)
+	if err != nil {
+		log.WithError(err).Error("failed to get code blobs")
+		return fmt.Errorf("get code blobs: %w", err)
+	}
+
 	solutionLog := log.WithField("code_type", "solution")
 
-	solutionRun, err := r.run(ctx, solutionLog, c)
+	solution, release, err := r.compile(ctx, solutionLog, c.Language,
+		c.Source)
+	if err != nil {
+		solutionLog.WithError(err).Error("failed to compile solution code")
+		return fmt.Errorf("compile solution code: %w", err)
+	}
+
+	var solutionRun *mycode.Run
+
+	if c.Interactive {
+		solutionRun, err = r.interact(ctx, log, solution, c)
+	} else {
+		solutionRun, err = r.run(ctx, solutionLog, solution, runParams{
+			stdin:       c.Stdin,
+			maxDuration: c.MaxDuration,
+			maxMemory:   c.MaxMemory,
+		})
+	}
+	release()
 	if err != nil {
 		solutionLog.WithError(err).Error("failed to run solution code")
 		return fmt.Errorf("run solution code: %w", err)
 	}
 
 	solutionRun.SolutionTestId = c.SolutionTestId
+	solutionRun.ReferenceTestId = c.ReferenceTestId
+	solutionRun.Attempt = c.Attempt
+	solutionRun.PlaygroundId = c.PlaygroundId
 
-	if c.WithChecker {
+	if c.WithChecker && solutionRun.Verdict == mycode.Verdict_accepted {
 
 		checkerLog := log.WithField("code_type", "checker")
 
-		checkerRun, err := r.run(ctx, checkerLog, &mycode.Code{
-			Language: c.CheckerLanguage,
-			Source:   c.CheckerSource,
-			Stdin:    solutionRun.Stdout,
-		})
+		checker, release, err := r.compile(ctx, checkerLog,
+			c.CheckerLanguage, c.CheckerSource)
+		if err != nil {
+			checkerLog.WithError(err).Error("failed to compile checker code")
+			return fmt.Errorf("compile checker code: %w", err)
+		}
+
+		params := runParams{
+			maxDuration: checkerMaxDuration,
+			maxMemory:   checkerMaxMemory,
+		}
+
+		if c.CheckerProtocol == mycode.CheckerProtocol_testlib_checker {
+			params.argFiles = []runFile{
+				{name: "input", content: c.Stdin},
+				{name: "output", content: solutionRun.Stdout},
+				{name: "answer", content: c.ExpectedStdout},
+			}
+		} else {
+			params.stdin = solutionRun.Stdout
+		}
+
+		checkerRun, err := r.run(ctx, checkerLog, checker, params)
+		release()
 		if err != nil {
 			checkerLog.WithError(err).Error("failed to run checker code")
 			return fmt.Errorf("run checker code: %w", err)
@@ -238,6 +713,28 @@ func (r *Runner) HandleCode(ctx context.Context, c *mycode.Code) (err error) {
 
 		solutionRun.CheckerStdout = checkerRun.Stdout
 		solutionRun.CheckerStderr = checkerRun.Stderr
+
+		switch checkerRun.Verdict {
+		case mycode.Verdict_compilation_error:
+			solutionRun.Verdict = mycode.Verdict_internal_error
+			solutionRun.CheckerStderr = checkerRun.CompilerOutput
+		case mycode.Verdict_internal_error:
+			solutionRun.Verdict = mycode.Verdict_internal_error
+		default:
+			if c.CheckerProtocol == mycode.CheckerProtocol_testlib_checker {
+				solutionRun.CheckerVerdict, solutionRun.Score,
+					solutionRun.CheckerMessage = checkResult(checkerRun)
+			} else {
+				solutionRun.CheckerVerdict, solutionRun.Score,
+					solutionRun.CheckerMessage = checkStdinOKResult(checkerRun)
+			}
+		}
+	}
+
+	err = r.putBlobs(ctx, solutionRun)
+	if err != nil {
+		log.WithError(err).Error("failed to put run blobs")
+		return fmt.Errorf("put run blobs: %w", err)
 	}
 
 	err = r.runPublisher.PublishRun(solutionRun)
@@ -248,6 +745,88 @@ func (r *Runner) HandleCode(ctx context.Context, c *mycode.Code) (err error) {
 	return nil
 }
 
+// FailCode publishes internal error run of the code which runner failed to
+// handle, so the code isn't waited forever. Failure cause is shown only for
+// reference codes, which are seen by teachers.
+func (r *Runner) FailCode(ctx context.Context, c *mycode.Code,
+	cause error) error {
+
+	run := &mycode.Run{
+		SolutionTestId:  c.SolutionTestId,
+		ReferenceTestId: c.ReferenceTestId,
+		Attempt:         c.Attempt,
+		PlaygroundId:    c.PlaygroundId,
+		Verdict:         mycode.Verdict_internal_error,
+	}
+
+	if c.ReferenceTestId != 0 {
+		run.Stderr = cause.Error()
+	}
+
+	err := r.runPublisher.PublishRun(run)
+	if err != nil {
+		return fmt.Errorf("publish run: %w", err)
+	}
+
+	return nil
+}
+
+// getBlobs sets code stdin and expected stdout stored in the blob storage.
+func (r *Runner) getBlobs(ctx context.Context, c *mycode.Code) error {
+
+	if c.StdinBlob != "" {
+		stdin, err := r.blobs.Get(ctx, c.StdinBlob)
+		if err != nil {
+			return fmt.Errorf("get stdin blob: %w", err)
+		}
+		c.Stdin = string(stdin)
+	}
+
+	if c.ExpectedStdoutBlob != "" {
+		expectedStdout, err := r.blobs.Get(ctx, c.ExpectedStdoutBlob)
+		if err != nil {
+			return fmt.Errorf("get expected stdout blob: %w", err)
+		}
+		c.ExpectedStdout = string(expectedStdout)
+	}
+
+	return nil
+}
+
+// putBlobs moves large run stdout and stderr to the blob storage, so they
+// don't bloat run message.
+func (r *Runner) putBlobs(ctx context.Context, run *mycode.Run) error {
+
+	if len(run.Stdout) > r.blobThreshold {
+		key, err := r.blobs.Put(ctx, []byte(run.Stdout))
+		if err != nil {
+			return fmt.Errorf("put stdout blob: %w", err)
+		}
+		run.Stdout, run.StdoutBlob = "", key
+	}
+
+	if len(run.Stderr) > r.blobThreshold {
+		key, err := r.blobs.Put(ctx, []byte(run.Stderr))
+		if err != nil {
+			return fmt.Errorf("put stderr blob: %w", err)
+		}
+		run.Stderr, run.StderrBlob = "", key
+	}
+
+	return nil
+}
+
+func compilerOutput(c *mycode.Compilation) string {
+	switch {
+	case c.Stdout == "":
+		return c.Stderr
+	case c.Stderr == "":
+		return c.Stdout
+	default:
+		return c.Stdout + "\n" + c.Stderr
+	}
+}
+
 func (r *Runner) createSrcFile(lang mycode.Language, content string) (
 	string, error) {
 
@@ -269,6 +848,31 @@ func (r *Runner) createSrcFile(lang mycode.Language, content string) (
 	return f.Name(), err
 }
 
+// createFiles creates directory with the files, which is readable by
+// sandboxed program.
+func (r *Runner) createFiles(fs []runFile) (string, error) {
+
+	path, err := ioutil.TempDir("", "files-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+
+	err = os.Chmod(path, 0755)
+	if err != nil {
+		return path, fmt.Errorf("chmod temp dir: %w", err)
+	}
+
+	for _, f := range fs {
+		err = ioutil.WriteFile(filepath.Join(path, f.name),
+			[]byte(f.content), 0644)
+		if err != nil {
+			return path, fmt.Errorf("write %s file: %w", f.name, err)
+		}
+	}
+
+	return path, nil
+}
+
 func (r *Runner) removeSrcFile(srcPath string) error {
 	return os.Remove(srcPath)
 }
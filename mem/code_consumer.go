@@ -0,0 +1,108 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/mycode"
+)
+
+type CodeHandler interface {
+	HandleCode(context.Context, *mycode.Code) error
+	// FailCode is called when code handling failed after all retries.
+	FailCode(context.Context, *mycode.Code, error) error
+}
+
+type CodeConsumer struct {
+	codeHandler  CodeHandler
+	transport    *Transport
+	log          *logrus.Entry
+	stopHandlers func()
+	wg           sync.WaitGroup
+}
+
+// NewCodeConsumer creates consumer handling transport codes with the given
+// parallelism.
+func NewCodeConsumer(t *Transport, parallelism int,
+	ch CodeHandler) *CodeConsumer {
+
+	cc := &CodeConsumer{
+		codeHandler: ch,
+		transport:   t,
+		log:         logrus.WithField("subsystem", "mem_code_consumer"),
+	}
+
+	var ctx context.Context
+
+	ctx, cc.stopHandlers = context.WithCancel(context.Background())
+
+	for i := 0; i < parallelism; i++ {
+		cc.wg.Add(1)
+		go func() {
+			defer cc.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case m := <-t.codes:
+					cc.handleMsg(ctx, m)
+				}
+			}
+		}()
+	}
+
+	return cc
+}
+
+func (cc *CodeConsumer) handleMsg(ctx context.Context, m codeMsg) {
+
+	log := cc.log.WithFields(logrus.Fields{
+		"solution_test_id": m.code.SolutionTestId,
+		"retry":            m.retry,
+	})
+
+	handleErr := cc.codeHandler.HandleCode(ctx, m.code)
+	if handleErr == nil || ctx.Err() != nil {
+		return
+	}
+
+	log.WithError(handleErr).Warn("failed to handle code")
+
+	if m.retry < len(retryDelays) {
+		cc.wg.Add(1)
+		go func() {
+			defer cc.wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelays[m.retry]):
+			}
+
+			err := cc.transport.publishCode(codeMsg{
+				code:  m.code,
+				retry: m.retry + 1,
+			})
+			if err != nil {
+				log.WithError(err).Error("failed to retry code")
+			}
+		}()
+		return
+	}
+
+	log.Error("code retries exhausted, dropped")
+
+	err := cc.codeHandler.FailCode(ctx, m.code, handleErr)
+	if err != nil {
+		log.WithError(err).Error("failed to handle code failure")
+	}
+}
+
+func (cc *CodeConsumer) Close() error {
+	cc.stopHandlers()
+	cc.wg.Wait()
+	return nil
+}
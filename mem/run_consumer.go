@@ -0,0 +1,108 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/mycode"
+)
+
+type RunHandler interface {
+	HandleRun(context.Context, *mycode.Run) error
+	// FailRun is called when run handling failed after all retries.
+	FailRun(context.Context, *mycode.Run, error) error
+}
+
+type RunConsumer struct {
+	runHandler   RunHandler
+	transport    *Transport
+	log          *logrus.Entry
+	stopHandlers func()
+	wg           sync.WaitGroup
+}
+
+// NewRunConsumer creates consumer handling transport runs with the given
+// parallelism.
+func NewRunConsumer(t *Transport, parallelism int,
+	ch RunHandler) *RunConsumer {
+
+	cc := &RunConsumer{
+		runHandler: ch,
+		transport:  t,
+		log:        logrus.WithField("subsystem", "mem_run_consumer"),
+	}
+
+	var ctx context.Context
+
+	ctx, cc.stopHandlers = context.WithCancel(context.Background())
+
+	for i := 0; i < parallelism; i++ {
+		cc.wg.Add(1)
+		go func() {
+			defer cc.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case m := <-t.runs:
+					cc.handleMsg(ctx, m)
+				}
+			}
+		}()
+	}
+
+	return cc
+}
+
+func (cc *RunConsumer) handleMsg(ctx context.Context, m runMsg) {
+
+	log := cc.log.WithFields(logrus.Fields{
+		"solution_test_id": m.run.SolutionTestId,
+		"retry":            m.retry,
+	})
+
+	handleErr := cc.runHandler.HandleRun(ctx, m.run)
+	if handleErr == nil || ctx.Err() != nil {
+		return
+	}
+
+	log.WithError(handleErr).Warn("failed to handle run")
+
+	if m.retry < len(retryDelays) {
+		cc.wg.Add(1)
+		go func() {
+			defer cc.wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelays[m.retry]):
+			}
+
+			err := cc.transport.publishRun(runMsg{
+				run:   m.run,
+				retry: m.retry + 1,
+			})
+			if err != nil {
+				log.WithError(err).Error("failed to retry run")
+			}
+		}()
+		return
+	}
+
+	log.Error("run retries exhausted, dropped")
+
+	err := cc.runHandler.FailRun(ctx, m.run, handleErr)
+	if err != nil {
+		log.WithError(err).Error("failed to handle run failure")
+	}
+}
+
+func (cc *RunConsumer) Close() error {
+	cc.stopHandlers()
+	cc.wg.Wait()
+	return nil
+}
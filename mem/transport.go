@@ -0,0 +1,88 @@
+// Package mem is the in-process transport of codes and runs between API
+// and runner working in the same process. It is used instead of rmq in
+// single server installs and tests, where RabbitMQ broker is overkill.
+// Messages live in memory only, so messages not handled before closing are
+// lost. Solution tests of the lost messages are republished by API stuck
+// solution tests reaper.
+package mem
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dimuls/mycode"
+)
+
+var ErrClosed = errors.New("transport closed")
+
+// retryDelays are delays before the message retries. Message failed after
+// the last retry is reported to handler as failed and dropped.
+var retryDelays = []time.Duration{
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+type codeMsg struct {
+	code  *mycode.Code
+	retry int
+}
+
+type runMsg struct {
+	run   *mycode.Run
+	retry int
+}
+
+// Transport is code and run queues. It implements pg.CodePublisher and
+// docker.RunPublisher.
+type Transport struct {
+	codes     chan codeMsg
+	runs      chan runMsg
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTransport creates transport with queues of capacity messages. Publish
+// blocks while the queue is full.
+func NewTransport(capacity int) *Transport {
+	return &Transport{
+		codes:  make(chan codeMsg, capacity),
+		runs:   make(chan runMsg, capacity),
+		closed: make(chan struct{}),
+	}
+}
+
+// Close makes publishing fail with ErrClosed.
+func (t *Transport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+	return nil
+}
+
+func (t *Transport) PublishCode(c *mycode.Code) error {
+	return t.publishCode(codeMsg{code: c})
+}
+
+func (t *Transport) publishCode(m codeMsg) error {
+	select {
+	case t.codes <- m:
+		return nil
+	case <-t.closed:
+		return ErrClosed
+	}
+}
+
+func (t *Transport) PublishRun(r *mycode.Run) error {
+	return t.publishRun(runMsg{run: r})
+}
+
+func (t *Transport) publishRun(m runMsg) error {
+	select {
+	case t.runs <- m:
+		return nil
+	case <-t.closed:
+		return ErrClosed
+	}
+}
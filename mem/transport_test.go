@@ -0,0 +1,278 @@
+package mem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dimuls/mycode"
+)
+
+const testTimeout = 5 * time.Second
+
+var errHandle = errors.New("handle failed")
+
+// fakeCodeHandler fails first failures handlings and reports every
+// handling and failure.
+type fakeCodeHandler struct {
+	failures int
+	handled  chan *mycode.Code
+	failed   chan error
+}
+
+func newFakeCodeHandler(failures int) *fakeCodeHandler {
+	return &fakeCodeHandler{
+		failures: failures,
+		handled:  make(chan *mycode.Code, 10),
+		failed:   make(chan error, 10),
+	}
+}
+
+func (h *fakeCodeHandler) HandleCode(ctx context.Context,
+	c *mycode.Code) error {
+
+	h.handled <- c
+
+	if h.failures > 0 {
+		h.failures--
+		return errHandle
+	}
+
+	return nil
+}
+
+func (h *fakeCodeHandler) FailCode(ctx context.Context, c *mycode.Code,
+	cause error) error {
+
+	h.failed <- cause
+	return nil
+}
+
+// fakeRunHandler fails first failures handlings and reports every
+// handling and failure.
+type fakeRunHandler struct {
+	failures int
+	handled  chan *mycode.Run
+	failed   chan error
+}
+
+func newFakeRunHandler(failures int) *fakeRunHandler {
+	return &fakeRunHandler{
+		failures: failures,
+		handled:  make(chan *mycode.Run, 10),
+		failed:   make(chan error, 10),
+	}
+}
+
+func (h *fakeRunHandler) HandleRun(ctx context.Context, r *mycode.Run) error {
+
+	h.handled <- r
+
+	if h.failures > 0 {
+		h.failures--
+		return errHandle
+	}
+
+	return nil
+}
+
+func (h *fakeRunHandler) FailRun(ctx context.Context, r *mycode.Run,
+	cause error) error {
+
+	h.failed <- cause
+	return nil
+}
+
+// shortenRetryDelays makes retries immediate until returned function is
+// called.
+func shortenRetryDelays() func() {
+	delays := retryDelays
+	retryDelays = make([]time.Duration, len(delays))
+	return func() {
+		retryDelays = delays
+	}
+}
+
+func TestCodeConsumer(t *testing.T) {
+
+	defer shortenRetryDelays()()
+
+	tests := []struct {
+		name        string
+		failures    int
+		wantHandled int
+		wantFailed  bool
+	}{{
+		name:        "handled",
+		failures:    0,
+		wantHandled: 1,
+	}, {
+		name:        "handled after retry",
+		failures:    2,
+		wantHandled: 3,
+	}, {
+		name:        "retries exhausted",
+		failures:    len(retryDelays) + 1,
+		wantHandled: len(retryDelays) + 1,
+		wantFailed:  true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			tr := NewTransport(1)
+			defer tr.Close()
+
+			h := newFakeCodeHandler(tt.failures)
+
+			cc := NewCodeConsumer(tr, 1, h)
+			defer cc.Close()
+
+			c := &mycode.Code{SolutionTestId: 1, Source: "source"}
+
+			err := tr.PublishCode(c)
+			if err != nil {
+				t.Fatalf("publish code: %v", err)
+			}
+
+			for i := 0; i < tt.wantHandled; i++ {
+				select {
+				case got := <-h.handled:
+					if got != c {
+						t.Fatalf("handled code = %v, want %v", got, c)
+					}
+				case <-time.After(testTimeout):
+					t.Fatalf("code handled %d times, want %d", i,
+						tt.wantHandled)
+				}
+			}
+
+			if tt.wantFailed {
+				select {
+				case err := <-h.failed:
+					if err != errHandle {
+						t.Errorf("fail cause = %v, want %v", err, errHandle)
+					}
+				case <-time.After(testTimeout):
+					t.Fatal("code failure not handled")
+				}
+			}
+
+			cc.Close()
+
+			select {
+			case got := <-h.handled:
+				t.Errorf("code %v handled more than %d times", got,
+					tt.wantHandled)
+			case err := <-h.failed:
+				t.Errorf("unexpected code failure: %v", err)
+			default:
+			}
+		})
+	}
+}
+
+func TestRunConsumer(t *testing.T) {
+
+	defer shortenRetryDelays()()
+
+	tests := []struct {
+		name        string
+		failures    int
+		wantHandled int
+		wantFailed  bool
+	}{{
+		name:        "handled",
+		failures:    0,
+		wantHandled: 1,
+	}, {
+		name:        "handled after retry",
+		failures:    2,
+		wantHandled: 3,
+	}, {
+		name:        "retries exhausted",
+		failures:    len(retryDelays) + 1,
+		wantHandled: len(retryDelays) + 1,
+		wantFailed:  true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			tr := NewTransport(1)
+			defer tr.Close()
+
+			h := newFakeRunHandler(tt.failures)
+
+			rc := NewRunConsumer(tr, 1, h)
+			defer rc.Close()
+
+			r := &mycode.Run{SolutionTestId: 1, Stdout: "stdout"}
+
+			err := tr.PublishRun(r)
+			if err != nil {
+				t.Fatalf("publish run: %v", err)
+			}
+
+			for i := 0; i < tt.wantHandled; i++ {
+				select {
+				case got := <-h.handled:
+					if got != r {
+						t.Fatalf("handled run = %v, want %v", got, r)
+					}
+				case <-time.After(testTimeout):
+					t.Fatalf("run handled %d times, want %d", i,
+						tt.wantHandled)
+				}
+			}
+
+			if tt.wantFailed {
+				select {
+				case err := <-h.failed:
+					if err != errHandle {
+						t.Errorf("fail cause = %v, want %v", err, errHandle)
+					}
+				case <-time.After(testTimeout):
+					t.Fatal("run failure not handled")
+				}
+			}
+
+			rc.Close()
+
+			select {
+			case got := <-h.handled:
+				t.Errorf("run %v handled more than %d times", got,
+					tt.wantHandled)
+			case err := <-h.failed:
+				t.Errorf("unexpected run failure: %v", err)
+			default:
+			}
+		})
+	}
+}
+
+func TestTransportClose(t *testing.T) {
+
+	tr := NewTransport(0)
+
+	err := tr.Close()
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	err = tr.PublishCode(&mycode.Code{})
+	if err != ErrClosed {
+		t.Errorf("publish code error = %v, want %v", err, ErrClosed)
+	}
+
+	err = tr.PublishRun(&mycode.Run{})
+	if err != ErrClosed {
+		t.Errorf("publish run error = %v, want %v", err, ErrClosed)
+	}
+
+	err = tr.Close()
+	if err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
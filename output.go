@@ -0,0 +1,27 @@
+package mycode
+
+import "unicode/utf8"
+
+// TruncatedMarker is appended to output truncated at the output limit.
+const TruncatedMarker = "\n... output truncated"
+
+// TruncateOutput truncates output larger than limit bytes and marks it
+// truncated. Rune cut by the limit is dropped, so output stays valid UTF-8.
+func TruncateOutput(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+
+	output = output[:limit]
+
+	for i := len(output) - 1; i >= 0 && i >= len(output)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(output[i]) {
+			if !utf8.FullRuneInString(output[i:]) {
+				output = output[:i]
+			}
+			break
+		}
+	}
+
+	return output + TruncatedMarker
+}
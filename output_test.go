@@ -0,0 +1,65 @@
+package mycode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateOutput(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		output string
+		limit  int
+		want   string
+	}{{
+		name:   "shorter than limit",
+		output: "abc",
+		limit:  4,
+		want:   "abc",
+	}, {
+		name:   "equal to limit",
+		output: "abc",
+		limit:  3,
+		want:   "abc",
+	}, {
+		name:   "longer than limit",
+		output: "abcdef",
+		limit:  3,
+		want:   "abc" + TruncatedMarker,
+	}, {
+		name:   "zero limit",
+		output: "abc",
+		limit:  0,
+		want:   TruncatedMarker,
+	}, {
+		name:   "two byte rune cut",
+		output: "abПривет",
+		limit:  3,
+		want:   "ab" + TruncatedMarker,
+	}, {
+		name:   "two byte rune not cut",
+		output: "abПривет",
+		limit:  4,
+		want:   "abП" + TruncatedMarker,
+	}, {
+		name:   "four byte rune cut",
+		output: "a😀b",
+		limit:  4,
+		want:   "a" + TruncatedMarker,
+	}, {
+		name:   "invalid UTF-8 kept",
+		output: strings.Repeat("\xff", 5),
+		limit:  3,
+		want:   "\xff\xff\xff" + TruncatedMarker,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateOutput(tt.output, tt.limit)
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/blob"
 )
 
 type CodePublisher interface {
@@ -22,27 +23,68 @@ type CodePublisher interface {
 }
 
 type MyCodeAPI struct {
-	jwtSecret     string
-	db            *sql.DB
-	codePublisher CodePublisher
-	stop          chan struct{}
-	wg            sync.WaitGroup
-	log           *logrus.Entry
+	pgURI             string
+	jwtSecret         string
+	db                *sql.DB
+	codePublisher     CodePublisher
+	blobs             blob.Storage
+	blobThreshold     int
+	storedOutputLimit int
+	codesLimiter      *limiter
+	events            *events
+	playground        *playground
+	outbox            chan struct{}
+	stop              chan struct{}
+	wg                sync.WaitGroup
+	log               *logrus.Entry
 }
 
-func NewMyCodeAPI(pgURI, jwtSecret string, cp CodePublisher) (*MyCodeAPI, error) {
+// NewMyCodeAPI creates API. Tests stdins, expected stdouts and runs outputs
+// larger than blobThreshold bytes are stored in the blob storage. Stored
+// runs outputs are truncated to storedOutputLimit bytes, since nobody reads
+// megabytes of output in the UI. Codes published by all API instances are
+// limited to codesRate per second with bursts of codesBurst codes, zero
+// codesRate disables limiting. Call Start after Migrate to run background
+// workers.
+func NewMyCodeAPI(pgURI, jwtSecret string, cp CodePublisher,
+	bs blob.Storage, blobThreshold, storedOutputLimit int,
+	codesRate float64, codesBurst int) (*MyCodeAPI, error) {
+
 	db, err := sql.Open("postgres", pgURI)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MyCodeAPI{
-		jwtSecret:     jwtSecret,
-		db:            db,
-		codePublisher: cp,
-		stop:          make(chan struct{}),
-		log:           logrus.WithField("subsystem", "pg_my_code_api"),
-	}, nil
+	api := &MyCodeAPI{
+		pgURI:             pgURI,
+		jwtSecret:         jwtSecret,
+		db:                db,
+		codePublisher:     cp,
+		blobs:             bs,
+		blobThreshold:     blobThreshold,
+		storedOutputLimit: storedOutputLimit,
+		codesLimiter:      newLimiter(db, codesRate, codesBurst),
+		events:            newEvents(),
+		playground:        newPlayground(),
+		outbox:            make(chan struct{}, 1),
+		stop:              make(chan struct{}),
+		log:               logrus.WithField("subsystem", "pg_my_code_api"),
+	}
+
+	return api, nil
+}
+
+// Start starts codes outbox relay, solution test events listening,
+// solutions similarity analysis, stuck solution tests reaping and
+// unreferenced blobs sweeping in background until API is closed. Workers
+// use the DB schema, so Start is called after Migrate.
+func (api *MyCodeAPI) Start() {
+	api.wg.Add(5)
+	go api.runOutboxRelay()
+	go api.runEventsListener()
+	go api.runSimilarity()
+	go api.runReaper()
+	go api.runBlobSweeper()
 }
 
 func (api *MyCodeAPI) Close() error {
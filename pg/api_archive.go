@@ -0,0 +1,298 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/archive"
+)
+
+func (api *MyCodeAPI) ExportExercise(ctx context.Context,
+	req *mycode.ExportExerciseReq) (*mycode.ExportExerciseResp, error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		e               = &mycode.Exercise{}
+		referenceSource sql.NullString
+	)
+
+	err = api.db.QueryRowContext(ctx, `
+		select title, description, language, estimator, reference_source
+		from exercise where id = $1
+	`, req.ExerciseId).Scan(&e.Title, &e.Description, &e.Language,
+		&e.Estimator, &referenceSource)
+	if err != nil {
+		return nil, fmt.Errorf("get exercise from DB: %w", err)
+	}
+
+	e.ReferenceSource = referenceSource.String
+
+	rows, err := api.db.QueryContext(ctx, `
+		select type, name, max_duration, max_memory, stdin, expected_stdout,
+			checker_language, checker_source, comparison_mode,
+			float_abs_epsilon, float_rel_epsilon, stdin_blob,
+			expected_stdout_blob, checker_protocol
+		from test
+		where exercise_id = $1
+		order by id
+	`, req.ExerciseId)
+	if err != nil {
+		return nil, fmt.Errorf("get tests from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	p := &archive.Package{Exercise: e, LanguageSet: true}
+
+	for rows.Next() {
+		var (
+			t                  = &mycode.Test{}
+			expectedStdout     sql.NullString
+			checkerLanguage    sql.NullInt32
+			checkerSource      sql.NullString
+			stdinBlob          sql.NullString
+			expectedStdoutBlob sql.NullString
+		)
+
+		err = rows.Scan(&t.Type, &t.Name, &t.MaxDuration, &t.MaxMemory,
+			&t.Stdin, &expectedStdout, &checkerLanguage, &checkerSource,
+			&t.ComparisonMode, &t.FloatAbsEpsilon, &t.FloatRelEpsilon,
+			&stdinBlob, &expectedStdoutBlob, &t.CheckerProtocol)
+		if err != nil {
+			return nil, fmt.Errorf("get test row from DB: %w", err)
+		}
+
+		t.Stdin, err = api.getContent(ctx, t.Stdin, stdinBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get test stdin: %w", err)
+		}
+
+		t.ExpectedStdout, err = api.getContent(ctx, expectedStdout.String,
+			expectedStdoutBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get test expected stdout: %w", err)
+		}
+
+		t.CheckerSource = checkerSource.String
+
+		if checkerLanguage.Valid {
+			t.CheckerLanguage = mycode.Language_name[checkerLanguage.Int32]
+		}
+
+		p.Tests = append(p.Tests, t)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("tests rows error: %w", rows.Err())
+	}
+
+	data, err := archive.Export(p)
+	if err != nil {
+		return nil, fmt.Errorf("export exercise: %w", err)
+	}
+
+	return &mycode.ExportExerciseResp{Archive: data}, nil
+}
+
+// ImportExercise adds exercise with its tests from own, Polygon or ICPC
+// package. Language of Polygon and ICPC packages must be set in request,
+// since they don't define solution language.
+func (api *MyCodeAPI) ImportExercise(ctx context.Context,
+	req *mycode.ImportExerciseReq) (*mycode.ImportExerciseResp, error) {
+
+	if len(req.Archive) == 0 {
+		return nil, fmt.Errorf("empty archive")
+	}
+
+	p, err := archive.Import(req.Archive)
+	if err != nil {
+		return nil, fmt.Errorf("import archive: %w", err)
+	}
+
+	e := p.Exercise
+
+	if req.LanguageSet || !p.LanguageSet {
+		e.Language = req.Language
+	}
+
+	if _, exists := mycode.Language_name[int32(e.Language)]; !exists {
+		return nil, fmt.Errorf("invalid language")
+	}
+
+	if _, exists := mycode.ExerciseEstimator_name[int32(e.Estimator)]; !exists {
+		return nil, fmt.Errorf("invalid estimator")
+	}
+
+	if e.Title == "" {
+		return nil, fmt.Errorf("empty title")
+	}
+
+	if e.Description == "" {
+		return nil, fmt.Errorf("empty description")
+	}
+
+	checkerLanguages := make([]sql.NullInt32, len(p.Tests))
+
+	for i, t := range p.Tests {
+		if t.Name == "" {
+			return nil, fmt.Errorf("test %d: empty name", i+1)
+		}
+
+		_, err = time.ParseDuration(t.MaxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("test %d: parse max_duration: %w", i+1, err)
+		}
+
+		_, err = parseBytes(t.MaxMemory)
+		if err != nil {
+			return nil, fmt.Errorf("test %d: parse max_memory: %w", i+1, err)
+		}
+
+		if t.FloatAbsEpsilon < 0 || t.FloatRelEpsilon < 0 {
+			return nil, fmt.Errorf("test %d: negative float epsilon", i+1)
+		}
+
+		switch t.Type {
+		case mycode.TestType_simple:
+		case mycode.TestType_checker, mycode.TestType_interactive:
+			l, exists := mycode.Language_value[t.CheckerLanguage]
+			if !exists {
+				return nil, fmt.Errorf("test %d: invalid checker language",
+					i+1)
+			}
+			if t.CheckerSource == "" {
+				return nil, fmt.Errorf("test %d: empty checker source", i+1)
+			}
+			if _, exists := mycode.CheckerProtocol_name[int32(t.CheckerProtocol)]; !exists {
+				return nil, fmt.Errorf("test %d: invalid checker protocol",
+					i+1)
+			}
+			checkerLanguages[i] = sql.NullInt32{Int32: l, Valid: true}
+		default:
+			return nil, fmt.Errorf("test %d: invalid type", i+1)
+		}
+	}
+
+	teacher, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	var referenceSource sql.NullString
+
+	if e.ReferenceSource != "" {
+		referenceSource.String = e.ReferenceSource
+		referenceSource.Valid = true
+	}
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `
+		insert into exercise (
+			teacher_id, title, description, language, estimator,
+			reference_source)
+		values ($1, $2, $3, $4, $5, $6)
+		returning id
+	`, teacher.Id, e.Title, e.Description, e.Language, e.Estimator,
+		referenceSource).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("add exercise to DB: %w", err)
+	}
+
+	var generateTestIDs []int64
+
+	for i, t := range p.Tests {
+		var checkerSource sql.NullString
+
+		if checkerLanguages[i].Valid {
+			checkerSource.String = t.CheckerSource
+			checkerSource.Valid = true
+		}
+
+		var (
+			testID             int64
+			stdin              string
+			stdinBlob          sql.NullString
+			expectedStdout     string
+			expectedStdoutBlob sql.NullString
+		)
+
+		stdin, stdinBlob, err = api.putContent(ctx, t.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("put test %d stdin: %w", i+1, err)
+		}
+
+		expectedStdout, expectedStdoutBlob, err = api.putContent(ctx,
+			t.ExpectedStdout)
+		if err != nil {
+			return nil, fmt.Errorf("put test %d expected stdout: %w", i+1,
+				err)
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			insert into test (
+				exercise_id, type, name, max_duration, max_memory, stdin,
+				expected_stdout, checker_language, checker_source,
+				comparison_mode, float_abs_epsilon, float_rel_epsilon,
+				stdin_blob, expected_stdout_blob, checker_protocol)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+				$14, $15)
+			returning id
+		`, id, t.Type, t.Name, t.MaxDuration, t.MaxMemory, stdin,
+			expectedStdout, checkerLanguages[i], checkerSource,
+			t.ComparisonMode, t.FloatAbsEpsilon, t.FloatRelEpsilon,
+			stdinBlob, expectedStdoutBlob, t.CheckerProtocol).
+			Scan(&testID)
+		if err != nil {
+			return nil, fmt.Errorf("add test %d to DB: %w", i+1, err)
+		}
+
+		if t.ExpectedStdout == "" {
+			generateTestIDs = append(generateTestIDs, testID)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	for _, testID := range generateTestIDs {
+		err = api.generateExpectedStdouts(ctx, 0, testID)
+		if err != nil {
+			return nil, fmt.Errorf("generate expected stdout: %w", err)
+		}
+	}
+
+	return &mycode.ImportExerciseResp{ExerciseId: id}, nil
+}
@@ -166,31 +166,48 @@ const (
 )
 
 var teacherMethods = map[string]struct{}{
-	"GetClasses":             {},
-	"GetStudents":            {},
-	"GetExercise":            {},
-	"AddExercise":            {},
-	"EditExercise":           {},
-	"RemoveExercise":         {},
-	"GetExercises":           {},
-	"GetExerciseAssignments": {},
-	"AssignExercise":         {},
-	"WithdrawExercise":       {},
-	"AddTest":                {},
-	"EditTest":               {},
-	"RemoveTest":             {},
-	"GetTests":               {},
-	"GetSolutions":           {},
-	"GetSolutionTests":       {},
+	"GetClasses":                {},
+	"GetStudents":               {},
+	"GetExercise":               {},
+	"AddExercise":               {},
+	"EditExercise":              {},
+	"RemoveExercise":            {},
+	"GetExercises":              {},
+	"GetExerciseAssignments":    {},
+	"AssignExercise":            {},
+	"WithdrawExercise":          {},
+	"AddTest":                   {},
+	"EditTest":                  {},
+	"RemoveTest":                {},
+	"GetTests":                  {},
+	"GetSolutions":              {},
+	"GetSolutionTests":          {},
+	"GetGrades":                 {},
+	"SolutionTestEvents":        {},
+	"GetEventsTicket":           {},
+	"RegenerateExpectedStdouts": {},
+	"RunCode":                   {},
+	"ExportExercise":            {},
+	"ImportExercise":            {},
+	"UploadTests":               {},
+	"RejudgeSolution":           {},
+	"RejudgeExercise":           {},
+	"RejudgeTest":               {},
+	"GetJudgingProgress":        {},
+	"GetSimilarityReport":       {},
 }
 
 var studentMethods = map[string]struct{}{
-	"GetExercise":      {},
-	"GetExercises":     {},
-	"GetTests":         {},
-	"AddSolution":      {},
-	"GetSolutions":     {},
-	"GetSolutionTests": {},
+	"GetExercise":        {},
+	"GetExercises":       {},
+	"GetTests":           {},
+	"AddSolution":        {},
+	"GetSolutions":       {},
+	"GetSolutionTests":   {},
+	"GetGrades":          {},
+	"SolutionTestEvents": {},
+	"GetEventsTicket":    {},
+	"RunCode":            {},
 }
 
 func (api *MyCodeAPI) Authorize(ctx context.Context, method string) (
@@ -215,7 +232,15 @@ func (api *MyCodeAPI) Authorize(ctx context.Context, method string) (
 		return ctx, fmt.Errorf("unexpected claims type: %T", token.Claims)
 	}
 
-	switch claims.UserRole {
+	return api.authorizeUser(ctx, method, claims.UserRole, claims.UserID)
+}
+
+// authorizeUser checks that method is allowed for the user role and puts
+// user role and user's teacher or student to the context.
+func (api *MyCodeAPI) authorizeUser(ctx context.Context, method,
+	userRole string, userID int64) (context.Context, error) {
+
+	switch userRole {
 
 	case jwtTeacher:
 		_, exists := teacherMethods[method]
@@ -224,7 +249,7 @@ func (api *MyCodeAPI) Authorize(ctx context.Context, method string) (
 				method)
 		}
 
-		t, err := api.teacher(ctx, claims.UserID)
+		t, err := api.teacher(ctx, userID)
 		if err != nil {
 			return ctx, fmt.Errorf("get teacher: %w", err)
 		}
@@ -239,7 +264,7 @@ func (api *MyCodeAPI) Authorize(ctx context.Context, method string) (
 				method)
 		}
 
-		t, err := api.student(ctx, claims.UserID)
+		t, err := api.student(ctx, userID)
 		if err != nil {
 			return ctx, fmt.Errorf("get student: %w", err)
 		}
@@ -248,7 +273,7 @@ func (api *MyCodeAPI) Authorize(ctx context.Context, method string) (
 		ctx = context.WithValue(ctx, ctxStudent, t)
 
 	default:
-		return ctx, fmt.Errorf("unexpected role: %v", claims.UserRole)
+		return ctx, fmt.Errorf("unexpected role: %v", userRole)
 	}
 
 	return ctx, nil
@@ -0,0 +1,232 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	solutionTestEventsMethod = "SolutionTestEvents"
+
+	eventsKeepAlivePeriod = 30 * time.Second
+
+	// eventsTicketTTL is the time events ticket can be used in.
+	eventsTicketTTL = 30 * time.Second
+)
+
+// ServeSolutionTestEvents streams solution test updates as server-sent
+// events to the student who owns the solution and to the student's teacher.
+// Since browser's EventSource can't set headers, single use ticket got by
+// GetEventsTicket can be passed with the ticket query parameter instead of
+// JWT, so JWT doesn't get to the URL and access logs.
+func (api *MyCodeAPI) ServeSolutionTestEvents(w http.ResponseWriter,
+	r *http.Request) {
+
+	var (
+		ctx = r.Context()
+		err error
+	)
+
+	if _, ok := ctx.Value(jwtCtxKey).(string); ok {
+		ctx, err = api.Authorize(ctx, solutionTestEventsMethod)
+	} else {
+		ctx, err = api.authorizeTicket(ctx, solutionTestEventsMethod,
+			r.URL.Query().Get("ticket"))
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	s, err := api.eventsSubscriberFromContext(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	log := api.log.WithFields(logrus.Fields{
+		"user_role": s.role,
+		"user_id":   s.id,
+	})
+
+	ch := api.events.subscribe(s)
+	defer api.events.unsubscribe(s, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	marshaler := &jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case st, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var buf bytes.Buffer
+
+			err = marshaler.Marshal(&buf, st)
+			if err != nil {
+				log.WithError(err).Error(
+					"failed to JSON marshal solution test")
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "event: solution_test\ndata: %s\n\n",
+				buf.String())
+		}
+		if err != nil {
+			log.WithError(err).Debug("failed to write event")
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// GetEventsTicket returns single use ticket authorizing solution test events
+// stream of the user.
+func (api *MyCodeAPI) GetEventsTicket(ctx context.Context,
+	req *mycode.GetEventsTicketReq) (*mycode.GetEventsTicketResp, error) {
+
+	ur, err := api.userRoleFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get user role from context: %w", err)
+	}
+
+	var userID int64
+
+	switch ur {
+	case ctxTeacher:
+		t, err := api.teacherFromContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get teacher from context: %w", err)
+		}
+		userID = t.UserId
+
+	case ctxStudent:
+		s, err := api.studentFromContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get student from context: %w", err)
+		}
+		userID = s.UserId
+
+	default:
+		return nil, fmt.Errorf("unexpected user role: %s", ur)
+	}
+
+	ticketBytes := make([]byte, 16)
+
+	_, err = rand.Read(ticketBytes)
+	if err != nil {
+		return nil, fmt.Errorf("generate ticket: %w", err)
+	}
+
+	ticket := hex.EncodeToString(ticketBytes)
+
+	_, err = api.db.ExecContext(ctx, `
+		insert into events_ticket (ticket, user_id, user_role, expires_at)
+		values ($1, $2, $3, now() + $4 * interval '1 second')
+	`, ticket, userID, ur, eventsTicketTTL.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("add events ticket to DB: %w", err)
+	}
+
+	return &mycode.GetEventsTicketResp{Ticket: ticket}, nil
+}
+
+// authorizeTicket authorizes method with the events ticket. Ticket is
+// deleted when used, expired tickets are deleted along.
+func (api *MyCodeAPI) authorizeTicket(ctx context.Context, method,
+	ticket string) (context.Context, error) {
+
+	if ticket == "" {
+		return ctx, fmt.Errorf("missing ticket")
+	}
+
+	var (
+		userID   int64
+		userRole string
+	)
+
+	err := api.db.QueryRowContext(ctx, `
+		with expired as (
+			delete from events_ticket where expires_at < now()
+		)
+		delete from events_ticket
+		where ticket = $1 and expires_at >= now()
+		returning user_id, user_role
+	`, ticket).Scan(&userID, &userRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ctx, fmt.Errorf("invalid or expired ticket")
+		}
+		return ctx, fmt.Errorf("delete events ticket from DB: %w", err)
+	}
+
+	return api.authorizeUser(ctx, method, userRole, userID)
+}
+
+// CloseEvents ends all solution test event streams, so HTTP server can be
+// gracefully shut down.
+func (api *MyCodeAPI) CloseEvents() {
+	api.events.close()
+}
+
+func (api *MyCodeAPI) eventsSubscriberFromContext(ctx context.Context) (
+	eventsSubscriber, error) {
+
+	ur, err := api.userRoleFromContext(ctx)
+	if err != nil {
+		return eventsSubscriber{}, fmt.Errorf(
+			"get user role from context: %w", err)
+	}
+
+	switch ur {
+	case ctxTeacher:
+		t, err := api.teacherFromContext(ctx)
+		if err != nil {
+			return eventsSubscriber{}, fmt.Errorf(
+				"get teacher from context: %w", err)
+		}
+		return eventsSubscriber{role: ctxTeacher, id: t.Id}, nil
+
+	case ctxStudent:
+		s, err := api.studentFromContext(ctx)
+		if err != nil {
+			return eventsSubscriber{}, fmt.Errorf(
+				"get student from context: %w", err)
+		}
+		return eventsSubscriber{role: ctxStudent, id: s.Id}, nil
+
+	default:
+		return eventsSubscriber{}, fmt.Errorf("unexpected user role: %s", ur)
+	}
+}
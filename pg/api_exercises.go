@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dimuls/mycode"
 )
@@ -44,17 +45,31 @@ func (api *MyCodeAPI) GetExercise(ctx context.Context,
 		return nil, fmt.Errorf("unexpected user role: %s", ur)
 	}
 
-	e := &mycode.Exercise{}
+	var (
+		e                  = &mycode.Exercise{}
+		referenceSource    sql.NullString
+		submissionCooldown sql.NullString
+	)
 
 	err = api.db.QueryRowContext(ctx, `
-		select id, teacher_id, title, description, language, estimator
+		select id, teacher_id, title, description, language, estimator,
+			reference_source, max_attempts, submission_cooldown
 		from exercise where id = $1
 	`, req.ExerciseId).Scan(&e.Id, &e.TeacherId, &e.Title, &e.Description,
-		&e.Language, &e.Estimator)
+		&e.Language, &e.Estimator, &referenceSource, &e.MaxAttempts,
+		&submissionCooldown)
 	if err != nil {
 		return nil, fmt.Errorf("get exercise from DB: %w", err)
 	}
 
+	e.SubmissionCooldown = submissionCooldown.String
+
+	// Reference solution is the answer to the exercise, so it is hidden
+	// from students.
+	if ur == ctxTeacher {
+		e.ReferenceSource = referenceSource.String
+	}
+
 	return &mycode.GetExerciseResp{Exercise: e}, nil
 }
 
@@ -69,19 +84,38 @@ func (api *MyCodeAPI) AddExercise(ctx context.Context,
 		return nil, fmt.Errorf("empty text")
 	}
 
+	if req.MaxAttempts < 0 {
+		return nil, fmt.Errorf("negative max_attempts")
+	}
+
+	submissionCooldown, err := parseCooldown(req.SubmissionCooldown)
+	if err != nil {
+		return nil, fmt.Errorf("invalid submission_cooldown: %w", err)
+	}
+
 	t, err := api.teacherFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get teacher from context: %w", err)
 	}
 
-	var id int64
+	var (
+		id              int64
+		referenceSource sql.NullString
+	)
+
+	if req.ReferenceSource != "" {
+		referenceSource.String = req.ReferenceSource
+		referenceSource.Valid = true
+	}
 
 	err = api.db.QueryRowContext(ctx, `
 		insert into exercise (
-			teacher_id, title, description, language, estimator)
-		values ($1, $2, $3, $4, $5)
+			teacher_id, title, description, language, estimator,
+			reference_source, max_attempts, submission_cooldown)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
 		returning id
-	`, t.Id, req.Title, req.Description, req.Language, req.Estimator).Scan(&id)
+	`, t.Id, req.Title, req.Description, req.Language, req.Estimator,
+		referenceSource, req.MaxAttempts, submissionCooldown).Scan(&id)
 
 	return &mycode.AddExerciseResp{
 		ExerciseId: id,
@@ -152,6 +186,34 @@ func (api *MyCodeAPI) EditExercise(ctx context.Context,
 		sets = append(sets, fmt.Sprintf("estimator = $%d", len(args)))
 	}
 
+	if req.ReferenceSourceSet {
+		var referenceSource sql.NullString
+		if req.ReferenceSource != "" {
+			referenceSource.String = req.ReferenceSource
+			referenceSource.Valid = true
+		}
+		args = append(args, referenceSource)
+		sets = append(sets, fmt.Sprintf("reference_source = $%d", len(args)))
+	}
+
+	if req.MaxAttemptsSet {
+		if req.MaxAttempts < 0 {
+			return nil, fmt.Errorf("negative max_attempts")
+		}
+		args = append(args, req.MaxAttempts)
+		sets = append(sets, fmt.Sprintf("max_attempts = $%d", len(args)))
+	}
+
+	if req.SubmissionCooldownSet {
+		submissionCooldown, err := parseCooldown(req.SubmissionCooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid submission_cooldown: %w", err)
+		}
+		args = append(args, submissionCooldown)
+		sets = append(sets, fmt.Sprintf("submission_cooldown = $%d",
+			len(args)))
+	}
+
 	if len(sets) == 0 {
 		return nil, fmt.Errorf("nothing changed")
 	}
@@ -225,7 +287,9 @@ func (api *MyCodeAPI) GetExercises(ctx context.Context,
 		if req.StudentId != 0 {
 			rows, err = api.db.QueryContext(ctx, `
 				select e.id, e.teacher_id, e.title, e.description,
-					e.language, e.estimator
+					e.language, e.estimator, e.reference_source,
+					e.max_attempts, e.submission_cooldown, se.opens_at,
+					se.closes_at, se.late_policy, se.late_penalty
 				from exercise as e
 				join student_exercise as se on e.id = se.exercise_id
 				where e.teacher_id = $1 and se.student_id = $2
@@ -233,7 +297,8 @@ func (api *MyCodeAPI) GetExercises(ctx context.Context,
 		} else {
 			rows, err = api.db.QueryContext(ctx, `
 				select id, teacher_id, title, description, language,
-					estimator
+					estimator, reference_source, max_attempts,
+					submission_cooldown, null, null, 0, 0
 				from exercise
 				where teacher_id = $1
 			`, t.Id)
@@ -250,7 +315,8 @@ func (api *MyCodeAPI) GetExercises(ctx context.Context,
 
 		rows, err = api.db.QueryContext(ctx, `
 			select e.id, e.teacher_id, e.title, e.description, e.language,
-				e.estimator
+				e.estimator, null, e.max_attempts, e.submission_cooldown,
+				se.opens_at, se.closes_at, se.late_policy, se.late_penalty
 			from student_exercise as se
 			join exercise as e on se.exercise_id = e.id
 			where se.student_id = $1
@@ -266,12 +332,23 @@ func (api *MyCodeAPI) GetExercises(ctx context.Context,
 	var es []*mycode.Exercise
 
 	for rows.Next() {
-		e := &mycode.Exercise{}
+		var (
+			e                  = &mycode.Exercise{}
+			referenceSource    sql.NullString
+			submissionCooldown sql.NullString
+			opensAt, closesAt  sql.NullTime
+		)
 		err := rows.Scan(&e.Id, &e.TeacherId, &e.Title, &e.Description,
-			&e.Language, &e.Estimator)
+			&e.Language, &e.Estimator, &referenceSource, &e.MaxAttempts,
+			&submissionCooldown, &opensAt, &closesAt, &e.LatePolicy,
+			&e.LatePenalty)
 		if err != nil {
 			return nil, fmt.Errorf("get exercise row from DB: %w", err)
 		}
+		e.ReferenceSource = referenceSource.String
+		e.SubmissionCooldown = submissionCooldown.String
+		e.OpensAt = formatTime(opensAt)
+		e.ClosesAt = formatTime(closesAt)
 		es = append(es, e)
 	}
 
@@ -383,6 +460,30 @@ func (api *MyCodeAPI) AssignExercise(ctx context.Context,
 		return nil, err
 	}
 
+	opensAt, err := parseTime(req.OpensAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opens_at: %w", err)
+	}
+
+	closesAt, err := parseTime(req.ClosesAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid closes_at: %w", err)
+	}
+
+	if opensAt.Valid && closesAt.Valid && !closesAt.Time.After(opensAt.Time) {
+		return nil, fmt.Errorf("closes_at is not after opens_at")
+	}
+
+	if _, exists := mycode.LatePolicy_name[int32(req.LatePolicy)]; !exists {
+		return nil, fmt.Errorf("invalid late_policy")
+	}
+
+	if req.LatePenalty < 0 || req.LatePenalty > 1 {
+		return nil, fmt.Errorf("late_penalty is not in [0, 1]")
+	}
+
+	// Assigning already assigned exercise updates its window and late
+	// policy.
 	switch {
 	case req.ClassId != 0:
 		err = api.checkClassBelongsToTeacher(ctx, req.ClassId, t.Id)
@@ -391,9 +492,17 @@ func (api *MyCodeAPI) AssignExercise(ctx context.Context,
 		}
 
 		_, err = api.db.ExecContext(ctx, `
-			insert into student_exercise (student_id, exercise_id) 
-				select id, $1 from student where class_id = $2
-		`, req.ExerciseId, req.ClassId)
+			insert into student_exercise (student_id, exercise_id, opens_at,
+				closes_at, late_policy, late_penalty)
+				select id, $1, $3, $4, $5, $6 from student
+				where class_id = $2
+			on conflict (student_id, exercise_id) do update set
+				opens_at = excluded.opens_at,
+				closes_at = excluded.closes_at,
+				late_policy = excluded.late_policy,
+				late_penalty = excluded.late_penalty
+		`, req.ExerciseId, req.ClassId, opensAt, closesAt, req.LatePolicy,
+			req.LatePenalty)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"add students exercises to DB: %w", err)
@@ -406,9 +515,16 @@ func (api *MyCodeAPI) AssignExercise(ctx context.Context,
 		}
 
 		_, err = api.db.ExecContext(ctx, `
-			insert into student_exercise (student_id, exercise_id)
-			values ($1, $2)
-		`, req.StudentId, req.ExerciseId)
+			insert into student_exercise (student_id, exercise_id, opens_at,
+				closes_at, late_policy, late_penalty)
+			values ($1, $2, $3, $4, $5, $6)
+			on conflict (student_id, exercise_id) do update set
+				opens_at = excluded.opens_at,
+				closes_at = excluded.closes_at,
+				late_policy = excluded.late_policy,
+				late_penalty = excluded.late_penalty
+		`, req.StudentId, req.ExerciseId, opensAt, closesAt, req.LatePolicy,
+			req.LatePenalty)
 		if err != nil {
 			return nil, fmt.Errorf("add student exercise to DB: %w", err)
 		}
@@ -475,3 +591,42 @@ func (api *MyCodeAPI) WithdrawExercise(ctx context.Context,
 
 	return &mycode.WithdrawExerciseResp{}, nil
 }
+
+// parseTime parses optional RFC 3339 timestamp.
+func parseTime(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+func formatTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+// parseCooldown validates optional submission cooldown duration.
+func parseCooldown(s string) (sql.NullString, error) {
+	if s == "" {
+		return sql.NullString{}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	if d < 0 {
+		return sql.NullString{}, fmt.Errorf("negative duration")
+	}
+
+	return sql.NullString{String: s, Valid: true}, nil
+}
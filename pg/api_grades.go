@@ -0,0 +1,100 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+func (api *MyCodeAPI) GetGrades(ctx context.Context,
+	req *mycode.GetGradesReq) (*mycode.GetGradesResp, error) {
+
+	ur, err := api.userRoleFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get user role from context: %w", err)
+	}
+
+	var (
+		wheres []string
+		args   []interface{}
+	)
+
+	switch ur {
+	case ctxTeacher:
+		if req.ExerciseId == 0 && req.StudentId == 0 {
+			return nil, fmt.Errorf("both exercise_id and student_id empty")
+		}
+
+		t, err := api.teacherFromContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get teacher from context: %w", err)
+		}
+
+		if req.ExerciseId != 0 {
+			err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if req.StudentId != 0 {
+			err = api.checkStudentBelongsToTeacher(ctx, req.StudentId, t.Id)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	case ctxStudent:
+		s, err := api.studentFromContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get student from context: %w", err)
+		}
+
+		req.StudentId = s.Id
+
+	default:
+		return nil, fmt.Errorf("unexpected user role: %s", ur)
+	}
+
+	if req.ExerciseId != 0 {
+		args = append(args, req.ExerciseId)
+		wheres = append(wheres, fmt.Sprintf("exercise_id = $%d", len(args)))
+	}
+
+	if req.StudentId != 0 {
+		args = append(args, req.StudentId)
+		wheres = append(wheres, fmt.Sprintf("student_id = $%d", len(args)))
+	}
+
+	wheres = append(wheres, "grade is not null")
+
+	rows, err := api.db.QueryContext(ctx, fmt.Sprintf(`
+		select distinct on (student_id, exercise_id)
+			student_id, exercise_id, id, grade
+		from solution
+		where %s
+		order by student_id, exercise_id, grade desc, id desc
+	`, strings.Join(wheres, " and ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("get grades from DB: %w", err)
+	}
+
+	var gs []*mycode.Grade
+
+	for rows.Next() {
+		g := &mycode.Grade{}
+		err = rows.Scan(&g.StudentId, &g.ExerciseId, &g.SolutionId, &g.Grade)
+		if err != nil {
+			return nil, fmt.Errorf("get grade row from DB: %w", err)
+		}
+		gs = append(gs, g)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("grades rows error: %w", rows.Err())
+	}
+
+	return &mycode.GetGradesResp{Grades: gs}, nil
+}
@@ -0,0 +1,218 @@
+package pg
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	playgroundMaxDuration = "2s"
+	playgroundMaxMemory   = "256MB"
+
+	// playgroundRunTimeout includes time spent in queues and compilation.
+	playgroundRunTimeout = 2 * time.Minute
+
+	// playgroundPollInterval is the interval of playground run polling in
+	// case its notification is lost.
+	playgroundPollInterval = 5 * time.Second
+
+	playgroundRunsChannel = "playground_runs"
+)
+
+// RunCode runs source with custom stdin in the sandbox without saving it.
+// Code is published through outbox, so it is limited with the other codes.
+// Run can be handled by any API instance, it is passed to the waiting one
+// through DB.
+func (api *MyCodeAPI) RunCode(ctx context.Context,
+	req *mycode.RunCodeReq) (*mycode.RunCodeResp, error) {
+
+	if _, exists := mycode.Language_name[int32(req.Language)]; !exists {
+		return nil, fmt.Errorf("invalid language")
+	}
+
+	if req.Source == "" {
+		return nil, fmt.Errorf("empty source")
+	}
+
+	busy, err := api.codesLimiter.busy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check runners busy: %w", err)
+	}
+
+	if busy {
+		return nil, fmt.Errorf("runners are busy, try again later")
+	}
+
+	idBytes := make([]byte, 16)
+
+	_, err = rand.Read(idBytes)
+	if err != nil {
+		return nil, fmt.Errorf("generate playground ID: %w", err)
+	}
+
+	id := hex.EncodeToString(idBytes)
+
+	stdin, stdinBlob, err := api.putContent(ctx, req.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("put stdin: %w", err)
+	}
+
+	runs := api.playground.wait(id)
+	defer api.playground.cancel(id)
+
+	err = api.enqueuePlaygroundCode(ctx, &mycode.Code{
+		Language:     req.Language,
+		Source:       req.Source,
+		Stdin:        stdin,
+		StdinBlob:    stdinBlob.String,
+		MaxDuration:  playgroundMaxDuration,
+		MaxMemory:    playgroundMaxMemory,
+		PlaygroundId: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(playgroundRunTimeout)
+	defer timeout.Stop()
+
+	poll := time.NewTicker(playgroundPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, fmt.Errorf("run timeout")
+		case <-api.stop:
+			return nil, fmt.Errorf("API stopped")
+		case <-runs:
+		case <-poll.C:
+		}
+
+		r, found, err := api.takePlaygroundRun(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("take playground run: %w", err)
+		}
+
+		if !found {
+			continue
+		}
+
+		stdout, err := api.getRunOutput(ctx, r.Stdout, r.StdoutBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get stdout: %w", err)
+		}
+
+		stderr, err := api.getRunOutput(ctx, r.Stderr, r.StderrBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get stderr: %w", err)
+		}
+
+		return &mycode.RunCodeResp{
+			Verdict:        r.Verdict,
+			Duration:       r.Duration,
+			WallDuration:   r.WallDuration,
+			UsedMemory:     r.UsedMemory,
+			Stdout:         mycode.TruncateOutput(stdout, api.storedOutputLimit),
+			Stderr:         mycode.TruncateOutput(stderr, api.storedOutputLimit),
+			CompilerOutput: r.CompilerOutput,
+			ExitCode:       r.ExitCode,
+			Signal:         r.Signal,
+		}, nil
+	}
+}
+
+// handlePlaygroundRun stores playground run and notifies API instances, so
+// the one running RunCode takes it. Duplicate runs are dropped.
+func (api *MyCodeAPI) handlePlaygroundRun(ctx context.Context,
+	r *mycode.Run) error {
+
+	runJSON, err := (&jsonpb.Marshaler{}).MarshalToString(r)
+	if err != nil {
+		return fmt.Errorf("JSON marshal run: %w", err)
+	}
+
+	_, err = api.db.ExecContext(ctx, `
+		with added as (
+			insert into playground_run (id, run) values ($1, $2)
+			on conflict (id) do nothing
+		)
+		select pg_notify($3, $1)
+	`, r.PlaygroundId, runJSON, playgroundRunsChannel)
+	if err != nil {
+		return fmt.Errorf("add playground run to DB: %w", err)
+	}
+
+	return nil
+}
+
+// takePlaygroundRun deletes and returns playground run if it is stored.
+func (api *MyCodeAPI) takePlaygroundRun(ctx context.Context, id string) (
+	*mycode.Run, bool, error) {
+
+	var runJSON string
+
+	err := api.db.QueryRowContext(ctx, `
+		delete from playground_run where id = $1 returning run
+	`, id).Scan(&runJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("delete playground run from DB: %w",
+			err)
+	}
+
+	r := &mycode.Run{}
+
+	err = jsonpb.UnmarshalString(runJSON, r)
+	if err != nil {
+		return nil, false, fmt.Errorf("JSON unmarshal run: %w", err)
+	}
+
+	return r, true, nil
+}
+
+// enqueuePlaygroundCode adds playground code to the outbox and wakes up
+// outbox relay.
+func (api *MyCodeAPI) enqueuePlaygroundCode(ctx context.Context,
+	c *mycode.Code) (err error) {
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	err = enqueueCodes(ctx, tx, []*mycode.Code{c})
+	if err != nil {
+		return fmt.Errorf("enqueue code: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	api.notifyOutbox()
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+func (api *MyCodeAPI) RegenerateExpectedStdouts(ctx context.Context,
+	req *mycode.RegenerateExpectedStdoutsReq) (
+	*mycode.RegenerateExpectedStdoutsResp, error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.checkExerciseHasReference(ctx, req.ExerciseId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.generateExpectedStdouts(ctx, req.ExerciseId, 0)
+	if err != nil {
+		return nil, fmt.Errorf("generate expected stdouts: %w", err)
+	}
+
+	return &mycode.RegenerateExpectedStdoutsResp{}, nil
+}
+
+func (api *MyCodeAPI) checkExerciseHasReference(ctx context.Context,
+	exerciseID int64) error {
+
+	var hasReference bool
+
+	err := api.db.QueryRowContext(ctx, `
+		select reference_source is not null from exercise where id = $1
+	`, exerciseID).Scan(&hasReference)
+	if err != nil {
+		return fmt.Errorf("get exercise reference from DB: %w", err)
+	}
+
+	if !hasReference {
+		return fmt.Errorf("exercise has no reference source")
+	}
+
+	return nil
+}
+
+// generateExpectedStdouts runs exercise reference source with the tests
+// stdins. Tests are selected by exercise or test ID. Tests of exercises
+// without reference source are skipped. Each generation increments test
+// attempt, so outdated reference runs are ignored. Generation pending
+// longer than stuck timeout is failed by reaper.
+func (api *MyCodeAPI) generateExpectedStdouts(ctx context.Context,
+	exerciseID, testID int64) (err error) {
+
+	var (
+		args   []interface{}
+		wheres []string
+	)
+
+	if exerciseID != 0 {
+		args = append(args, exerciseID)
+		wheres = append(wheres, fmt.Sprintf("t.exercise_id = $%d", len(args)))
+	}
+
+	if testID != 0 {
+		args = append(args, testID)
+		wheres = append(wheres, fmt.Sprintf("t.id = $%d", len(args)))
+	}
+
+	if len(wheres) == 0 {
+		return fmt.Errorf("both exercise_id and test_id are empty")
+	}
+
+	// Reference source of interactive test can't be run without interactor.
+	args = append(args, mycode.TestType_interactive)
+	wheres = append(wheres, fmt.Sprintf("t.type <> $%d", len(args)),
+		"t.exercise_id = e.id", "e.reference_source is not null")
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		update test as t set expected_stdout_pending = true,
+			expected_stdout_attempt = t.expected_stdout_attempt + 1,
+			expected_stdout_error = null,
+			expected_stdout_pending_since = now()
+		from exercise as e
+		where %s
+		returning t.id, t.stdin, t.stdin_blob, t.max_duration, t.max_memory,
+			t.expected_stdout_attempt, e.language, e.reference_source
+	`, strings.Join(wheres, " and ")), args...)
+	if err != nil {
+		return fmt.Errorf("update tests in DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var cs []*mycode.Code
+
+	for rows.Next() {
+		var (
+			c         = &mycode.Code{}
+			stdinBlob sql.NullString
+		)
+		err = rows.Scan(&c.ReferenceTestId, &c.Stdin, &stdinBlob,
+			&c.MaxDuration, &c.MaxMemory, &c.Attempt, &c.Language, &c.Source)
+		if err != nil {
+			return fmt.Errorf("get test row from DB: %w", err)
+		}
+		c.StdinBlob = stdinBlob.String
+		cs = append(cs, c)
+	}
+
+	if rows.Err() != nil {
+		err = rows.Err()
+		return fmt.Errorf("tests rows error: %w", err)
+	}
+
+	err = enqueueCodes(ctx, tx, cs)
+	if err != nil {
+		return fmt.Errorf("enqueue codes: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	api.notifyOutbox()
+
+	return nil
+}
+
+// handleReferenceRun sets test expected stdout from the reference run.
+func (api *MyCodeAPI) handleReferenceRun(ctx context.Context,
+	r *mycode.Run) (err error) {
+
+	log := api.log.WithField("reference_test_id", r.ReferenceTestId)
+
+	log.Info("reference run received")
+
+	defer func() {
+		if err != nil {
+			log.WithError(err).Error("failed to process reference run")
+		} else {
+			log.Info("reference run processed")
+		}
+	}()
+
+	if r.Verdict == mycode.Verdict_accepted {
+		var (
+			stdout     string
+			stdoutBlob sql.NullString
+		)
+		stdout, stdoutBlob, err = api.putRunOutput(ctx, r.Stdout,
+			r.StdoutBlob)
+		if err != nil {
+			return fmt.Errorf("put stdout: %w", err)
+		}
+		_, err = api.db.ExecContext(ctx, `
+			update test set expected_stdout = $1, expected_stdout_blob = $2,
+				expected_stdout_pending = false
+			where id = $3 and expected_stdout_attempt = $4
+				and expected_stdout_pending
+		`, stdout, stdoutBlob, r.ReferenceTestId, r.Attempt)
+	} else {
+		_, err = api.db.ExecContext(ctx, `
+			update test set expected_stdout_pending = false,
+				expected_stdout_error = $1
+			where id = $2 and expected_stdout_attempt = $3
+				and expected_stdout_pending
+		`, referenceRunError(r), r.ReferenceTestId, r.Attempt)
+	}
+	if err != nil {
+		return fmt.Errorf("update test expected stdout in DB: %w", err)
+	}
+
+	return nil
+}
+
+func referenceRunError(r *mycode.Run) string {
+	switch r.Verdict {
+	case mycode.Verdict_compilation_error:
+		return fmt.Sprintf("%s: %s", r.Verdict, r.CompilerOutput)
+	case mycode.Verdict_runtime_error:
+		return fmt.Sprintf("%s: exit code %d, signal %d: %s", r.Verdict,
+			r.ExitCode, r.Signal, r.Stderr)
+	default:
+		if r.Stderr != "" {
+			return fmt.Sprintf("%s: %s", r.Verdict, r.Stderr)
+		}
+		return r.Verdict.String()
+	}
+}
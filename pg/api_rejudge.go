@@ -0,0 +1,317 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dimuls/mycode"
+)
+
+func (api *MyCodeAPI) RejudgeSolution(ctx context.Context,
+	req *mycode.RejudgeSolutionReq) (*mycode.RejudgeSolutionResp, error) {
+
+	if req.SolutionId == 0 {
+		return nil, fmt.Errorf("empty solution_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkSolutionBelongsToTeacher(ctx, req.SolutionId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := api.rejudge(ctx, "s.id = $1", req.SolutionId)
+	if err != nil {
+		return nil, fmt.Errorf("rejudge: %w", err)
+	}
+
+	return &mycode.RejudgeSolutionResp{SolutionTestsCount: count}, nil
+}
+
+func (api *MyCodeAPI) RejudgeExercise(ctx context.Context,
+	req *mycode.RejudgeExerciseReq) (*mycode.RejudgeExerciseResp, error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := api.rejudge(ctx, "s.exercise_id = $1", req.ExerciseId)
+	if err != nil {
+		return nil, fmt.Errorf("rejudge: %w", err)
+	}
+
+	return &mycode.RejudgeExerciseResp{SolutionTestsCount: count}, nil
+}
+
+func (api *MyCodeAPI) RejudgeTest(ctx context.Context,
+	req *mycode.RejudgeTestReq) (*mycode.RejudgeTestResp, error) {
+
+	if req.TestId == 0 {
+		return nil, fmt.Errorf("empty test_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkTestBelongsToTeacher(ctx, req.TestId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := api.rejudge(ctx, "t.id = $1", req.TestId)
+	if err != nil {
+		return nil, fmt.Errorf("rejudge: %w", err)
+	}
+
+	return &mycode.RejudgeTestResp{SolutionTestsCount: count}, nil
+}
+
+// GetJudgingProgress returns count of the exercise solution tests and count
+// of them being judged.
+func (api *MyCodeAPI) GetJudgingProgress(ctx context.Context,
+	req *mycode.GetJudgingProgressReq) (*mycode.GetJudgingProgressResp,
+	error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &mycode.GetJudgingProgressResp{}
+
+	err = api.db.QueryRowContext(ctx, `
+		select count(*), count(*) filter (where st.status = $2)
+		from solution_test as st
+		join solution as s on st.solution_id = s.id
+		where s.exercise_id = $1
+	`, req.ExerciseId, mycode.SolutionTestStatus_processing).
+		Scan(&resp.Total, &resp.Processing)
+	if err != nil {
+		return nil, fmt.Errorf("get solution tests stats from DB: %w", err)
+	}
+
+	return resp, nil
+}
+
+// rejudge resets solution tests matching the condition on solution s and
+// test t to processing, adds missing solution tests and enqueues their
+// codes. Each rejudge increments solution test attempt, so runs of the
+// previous judging are ignored.
+func (api *MyCodeAPI) rejudge(ctx context.Context, where string,
+	id int64) (count int64, err error) {
+
+	var pending bool
+
+	err = api.db.QueryRowContext(ctx, fmt.Sprintf(`
+		select exists (
+			select 1 from solution as s
+			join test as t on t.exercise_id = s.exercise_id
+			where %s and t.expected_stdout_pending
+		)
+	`, where), id).Scan(&pending)
+	if err != nil {
+		return 0, fmt.Errorf("check expected stdouts pending: %w", err)
+	}
+
+	if pending {
+		return 0, fmt.Errorf("exercise expected stdouts are being generated")
+	}
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	count, err = resetSolutionTests(ctx, tx, where, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+
+	api.notifyOutbox()
+
+	return count, nil
+}
+
+// resetSolutionTests does rejudge in the transaction. Caller should notify
+// outbox after the commit.
+func resetSolutionTests(ctx context.Context, tx *sql.Tx, where string,
+	id int64) (int64, error) {
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		insert into solution_test (solution_id, test_id, status)
+		select s.id, t.id, $2
+		from solution as s
+		join test as t on t.exercise_id = s.exercise_id
+		where %s and not exists (
+			select 1 from solution_test as st
+			where st.solution_id = s.id and st.test_id = t.id
+		)
+	`, where), id, mycode.SolutionTestStatus_processing)
+	if err != nil {
+		return 0, fmt.Errorf("add missing solution tests to DB: %w",
+			err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		update solution as s set grade = null
+		where exists (
+			select 1 from test as t
+			where t.exercise_id = s.exercise_id and %s
+		)
+	`, where), id)
+	if err != nil {
+		return 0, fmt.Errorf("reset solutions grades in DB: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		update solution_test as st set status = $2,
+			attempt = st.attempt + 1, processing_since = now(),
+			republishes = 0, duration = null, wall_duration = null,
+			used_memory = null, stdout = null, stderr = null,
+			stdout_blob = null, stderr_blob = null, checker_stdout = null,
+			checker_stderr = null, checker_message = null,
+			compiler_output = null, fails = null, verdict = null,
+			exit_code = null, signal = null, score = null
+		from solution as s, test as t, exercise as e, student as stu,
+			class as c
+		where st.solution_id = s.id and st.test_id = t.id
+			and s.exercise_id = e.id and s.student_id = stu.id
+			and stu.class_id = c.id and %s
+		returning %s
+	`, where, solutionTestCodeColumns), id,
+		mycode.SolutionTestStatus_processing)
+	if err != nil {
+		return 0, fmt.Errorf("reset solution tests in DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		cs []*mycode.Code
+		es []solutionTestEvent
+	)
+
+	for rows.Next() {
+		var (
+			c *mycode.Code
+			e solutionTestEvent
+		)
+
+		c, err = scanSolutionTestCode(rows, &mycode.SolutionTest{},
+			&e.StudentID, &e.TeacherID)
+		if err != nil {
+			return 0, err
+		}
+
+		e.SolutionTestID = c.SolutionTestId
+
+		cs = append(cs, c)
+		es = append(es, e)
+	}
+
+	if rows.Err() != nil {
+		err = rows.Err()
+		return 0, fmt.Errorf("solution tests rows error: %w", err)
+	}
+
+	err = enqueueCodes(ctx, tx, cs)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue codes: %w", err)
+	}
+
+	for _, e := range es {
+		err = notifySolutionTest(ctx, tx, e)
+		if err != nil {
+			return 0, fmt.Errorf("notify solution test: %w", err)
+		}
+	}
+
+	return int64(len(cs)), nil
+}
+
+// solutionTestCodeColumns are columns of solution test, its solution s,
+// test t, exercise e and class c scanned by scanSolutionTestCode.
+const solutionTestCodeColumns = `st.id, st.attempt, s.id, t.id,
+	s.student_id, c.teacher_id, e.language, s.source, t.type, t.stdin,
+	t.stdin_blob, t.checker_language, t.checker_source, t.max_duration,
+	t.max_memory, t.expected_stdout, t.expected_stdout_blob,
+	t.checker_protocol`
+
+// scanSolutionTestCode scans solution test code and sets solution test IDs.
+func scanSolutionTestCode(rows *sql.Rows, st *mycode.SolutionTest,
+	studentID, teacherID *int64) (*mycode.Code, error) {
+
+	var (
+		c                  = &mycode.Code{}
+		testType           mycode.TestType
+		stdinBlob          sql.NullString
+		checkerLanguage    sql.NullInt32
+		checkerSource      sql.NullString
+		expectedStdout     sql.NullString
+		expectedStdoutBlob sql.NullString
+	)
+
+	err := rows.Scan(&c.SolutionTestId, &c.Attempt, &st.SolutionId,
+		&st.TestId, studentID, teacherID, &c.Language, &c.Source,
+		&testType, &c.Stdin, &stdinBlob, &checkerLanguage, &checkerSource,
+		&c.MaxDuration, &c.MaxMemory, &expectedStdout, &expectedStdoutBlob,
+		&c.CheckerProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("get solution test row from DB: %w", err)
+	}
+
+	c.StdinBlob = stdinBlob.String
+	c.CheckerLanguage = mycode.Language(checkerLanguage.Int32)
+	c.CheckerSource = checkerSource.String
+	c.WithChecker = testType == mycode.TestType_checker
+	c.Interactive = testType == mycode.TestType_interactive
+
+	if c.WithChecker || c.Interactive {
+		c.ExpectedStdout = expectedStdout.String
+		c.ExpectedStdoutBlob = expectedStdoutBlob.String
+	}
+
+	st.Id = c.SolutionTestId
+
+	return c, nil
+}
@@ -12,6 +12,14 @@ import (
 
 func (api *MyCodeAPI) HandleRun(ctx context.Context, r *mycode.Run) (err error) {
 
+	if r.PlaygroundId != "" {
+		return api.handlePlaygroundRun(ctx, r)
+	}
+
+	if r.ReferenceTestId != 0 {
+		return api.handleReferenceRun(ctx, r)
+	}
+
 	log := api.log.WithField("solution_test_id", r.SolutionTestId)
 
 	log.Info("run received")
@@ -26,48 +34,75 @@ func (api *MyCodeAPI) HandleRun(ctx context.Context, r *mycode.Run) (err error)
 
 	t := &mycode.Test{}
 
-	var expectedStdout sql.NullString
+	var (
+		solutionID, studentID, teacherID int64
+		expectedStdout                   sql.NullString
+		expectedStdoutBlob               sql.NullString
+	)
 
 	err = api.db.QueryRowContext(ctx, `
-		select t.type, t.max_duration, t.max_memory, t.expected_stdout
+		select st.solution_id, s.student_id, c.teacher_id, st.test_id,
+			t.type, t.max_duration, t.max_memory, t.expected_stdout,
+			t.comparison_mode, t.float_abs_epsilon, t.float_rel_epsilon,
+			t.expected_stdout_blob
 		from solution_test as st
 		join test as t on st.test_id = t.id
+		join solution as s on st.solution_id = s.id
+		join student as stu on s.student_id = stu.id
+		join class as c on stu.class_id = c.id
 		where st.id = $1 
-	`, r.SolutionTestId).Scan(&t.Type, &t.MaxDuration, &t.MaxMemory,
-		&expectedStdout)
+	`, r.SolutionTestId).Scan(&solutionID, &studentID, &teacherID, &t.Id,
+		&t.Type, &t.MaxDuration, &t.MaxMemory, &expectedStdout,
+		&t.ComparisonMode, &t.FloatAbsEpsilon, &t.FloatRelEpsilon,
+		&expectedStdoutBlob)
 	if err != nil {
 		return fmt.Errorf("get test from DB: %w", err)
 	}
 
-	t.ExpectedStdout = expectedStdout.String
-
-	runDuration, err := time.ParseDuration(r.Duration)
+	// Runner stores large outputs in the blob storage, they are fetched for
+	// judging and truncation.
+	r.Stdout, err = api.getRunOutput(ctx, r.Stdout, r.StdoutBlob)
 	if err != nil {
-		return fmt.Errorf("parse run duration: %w", err)
+		return fmt.Errorf("get stdout: %w", err)
 	}
 
-	maxDuration, err := time.ParseDuration(t.MaxDuration)
+	r.Stderr, err = api.getRunOutput(ctx, r.Stderr, r.StderrBlob)
 	if err != nil {
-		return fmt.Errorf("parse test duration: %w", err)
+		return fmt.Errorf("get stderr: %w", err)
 	}
 
-	runUsedMemory, err := parseBytes(r.UsedMemory)
+	if t.Type == mycode.TestType_simple {
+		t.ExpectedStdout, err = api.getContent(ctx, expectedStdout.String,
+			expectedStdoutBlob)
+		if err != nil {
+			return fmt.Errorf("get expected stdout: %w", err)
+		}
+	}
+
+	verdict, fails, err := judge(t, r)
 	if err != nil {
-		return fmt.Errorf("parse run used memory: %w", err)
+		return err
 	}
 
-	maxMemory, err := parseBytes(t.MaxMemory)
+	r.Stdout = mycode.TruncateOutput(r.Stdout, api.storedOutputLimit)
+	r.Stderr = mycode.TruncateOutput(r.Stderr, api.storedOutputLimit)
+	r.CheckerStdout = mycode.TruncateOutput(r.CheckerStdout,
+		api.storedOutputLimit)
+	r.CheckerStderr = mycode.TruncateOutput(r.CheckerStderr,
+		api.storedOutputLimit)
+
+	stdout, stdoutBlob, err := api.putContent(ctx, r.Stdout)
 	if err != nil {
-		return fmt.Errorf("parse max memory: %w", err)
+		return fmt.Errorf("put stdout: %w", err)
 	}
 
-	fails := &mycode.SolutionTestFails{
-		WrongDuration:   runDuration > maxDuration,
-		WrongUsedMemory: runUsedMemory > maxMemory,
-		WrongStdout:     t.Type == mycode.TestType_simple && r.Stdout != t.ExpectedStdout,
-		WrongChecker:    t.Type == mycode.TestType_checker && r.CheckerStdout != "ok",
+	stderr, stderrBlob, err := api.putContent(ctx, r.Stderr)
+	if err != nil {
+		return fmt.Errorf("put stderr: %w", err)
 	}
 
+	testScore := score(verdict, r)
+
 	failsJSON, err := json.Marshal(fails)
 	if err != nil {
 		return fmt.Errorf("JSON marshal fails: %w", err)
@@ -75,12 +110,9 @@ func (api *MyCodeAPI) HandleRun(ctx context.Context, r *mycode.Run) (err error)
 
 	var status mycode.SolutionTestStatus
 
-	failed := fails.WrongDuration || fails.WrongUsedMemory ||
-		fails.WrongStdout || fails.WrongChecker || r.Stderr != ""
-
 	var failsJSONStr sql.NullString
 
-	if failed {
+	if verdict != mycode.Verdict_accepted {
 		status = mycode.SolutionTestStatus_failed
 		failsJSONStr.String = string(failsJSON)
 		failsJSONStr.Valid = true
@@ -89,30 +121,196 @@ func (api *MyCodeAPI) HandleRun(ctx context.Context, r *mycode.Run) (err error)
 		failsJSONStr.Valid = false
 	}
 
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	// Run of the previous judging attempt doesn't match any solution test
+	// after rejudge, such run is stale and dropped. Codes are published
+	// at least once, so run already stored for the attempt is duplicate
+	// and dropped too.
+	var res sql.Result
+
 	switch t.Type {
 	case mycode.TestType_simple:
-		_, err = api.db.ExecContext(ctx, `
+		res, err = tx.ExecContext(ctx, `
 			update solution_test set status = $1, duration = $2,
 				used_memory = $3, stdout = $4, stderr = $5,
-				fails = $6
-			where id = $7
-		`, status, r.Duration, r.UsedMemory, r.Stdout, r.Stderr,
-			failsJSONStr, r.SolutionTestId)
-	case mycode.TestType_checker:
-		_, err = api.db.ExecContext(ctx, `
+				compiler_output = $6, fails = $7, verdict = $8,
+				exit_code = $9, signal = $10, wall_duration = $11,
+				score = $12, stdout_blob = $13, stderr_blob = $14
+			where id = $15 and attempt = $16 and status = $17
+		`, status, r.Duration, r.UsedMemory, stdout, stderr,
+			r.CompilerOutput, failsJSONStr, verdict, r.ExitCode, r.Signal,
+			r.WallDuration, testScore, stdoutBlob, stderrBlob,
+			r.SolutionTestId, r.Attempt,
+			mycode.SolutionTestStatus_processing)
+	case mycode.TestType_checker, mycode.TestType_interactive:
+		res, err = tx.ExecContext(ctx, `
 			update solution_test set status = $1, duration = $2,
 				used_memory = $3, stdout = $4, stderr = $5,
-				checker_stdout = $6, checker_stderr = $7,
-				fails = $8
-			where id = $9
-		`, status, r.Duration, r.UsedMemory, r.Stdout, r.Stderr,
-			r.CheckerStdout, r.CheckerStderr, failsJSONStr,
-			r.SolutionTestId)
+				compiler_output = $6, checker_stdout = $7,
+				checker_stderr = $8, fails = $9, verdict = $10,
+				exit_code = $11, signal = $12, wall_duration = $13,
+				score = $14, checker_message = $15, stdout_blob = $16,
+				stderr_blob = $17
+			where id = $18 and attempt = $19 and status = $20
+		`, status, r.Duration, r.UsedMemory, stdout, stderr,
+			r.CompilerOutput, r.CheckerStdout, r.CheckerStderr,
+			failsJSONStr, verdict, r.ExitCode, r.Signal, r.WallDuration,
+			testScore, r.CheckerMessage, stdoutBlob, stderrBlob,
+			r.SolutionTestId, r.Attempt,
+			mycode.SolutionTestStatus_processing)
 	}
 	if err != nil {
 		return fmt.Errorf("add solution test result to DB: %w",
 			err)
 	}
 
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get affected rows count: %w", err)
+	}
+
+	if affected == 0 {
+		log.WithField("attempt", r.Attempt).
+			Warn("stale or duplicate run dropped")
+		return tx.Rollback()
+	}
+
+	err = gradeSolution(ctx, tx, solutionID)
+	if err != nil {
+		return fmt.Errorf("grade solution: %w", err)
+	}
+
+	err = notifySolutionTest(ctx, tx, solutionTestEvent{
+		SolutionTestID: r.SolutionTestId,
+		StudentID:      studentID,
+		TeacherID:      teacherID,
+	})
+	if err != nil {
+		return fmt.Errorf("notify solution test: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit changes to DB: %w", err)
+	}
+
 	return nil
 }
+
+// FailRun stores internal error result of the run which API failed to
+// handle, so solution test doesn't stay processing forever. Failure cause
+// is shown only for reference runs, which are seen by teachers.
+func (api *MyCodeAPI) FailRun(ctx context.Context, r *mycode.Run,
+	cause error) error {
+
+	failed := &mycode.Run{
+		SolutionTestId:  r.SolutionTestId,
+		ReferenceTestId: r.ReferenceTestId,
+		Attempt:         r.Attempt,
+		PlaygroundId:    r.PlaygroundId,
+		Verdict:         mycode.Verdict_internal_error,
+	}
+
+	if r.ReferenceTestId != 0 {
+		failed.Stderr = cause.Error()
+	}
+
+	return api.HandleRun(ctx, failed)
+}
+
+// judge checks run against the test. Limits are checked before runtime
+// error, since program killed for exceeding them also looks crashed.
+func judge(t *mycode.Test, r *mycode.Run) (mycode.Verdict,
+	*mycode.SolutionTestFails, error) {
+
+	switch r.Verdict {
+	case mycode.Verdict_compilation_error:
+		return r.Verdict, &mycode.SolutionTestFails{CompilationError: true},
+			nil
+	case mycode.Verdict_internal_error, mycode.Verdict_unknown_verdict:
+		return mycode.Verdict_internal_error, &mycode.SolutionTestFails{},
+			nil
+	}
+
+	runDuration, err := time.ParseDuration(r.Duration)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse run duration: %w", err)
+	}
+
+	maxDuration, err := time.ParseDuration(t.MaxDuration)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse test duration: %w", err)
+	}
+
+	runUsedMemory, err := parseBytes(r.UsedMemory)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse run used memory: %w", err)
+	}
+
+	maxMemory, err := parseBytes(t.MaxMemory)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse max memory: %w", err)
+	}
+
+	fails := &mycode.SolutionTestFails{
+		WrongDuration: runDuration > maxDuration ||
+			r.Verdict == mycode.Verdict_time_limit_exceeded,
+		WrongUsedMemory: runUsedMemory > maxMemory ||
+			r.Verdict == mycode.Verdict_memory_limit_exceeded,
+		WrongOutputSize: r.Verdict == mycode.Verdict_output_limit_exceeded,
+		WrongStdout:     t.Type == mycode.TestType_simple && !compareStdout(t, r.Stdout),
+		WrongChecker: t.Type != mycode.TestType_simple &&
+			r.CheckerVerdict != mycode.CheckerVerdict_checker_ok,
+	}
+
+	switch {
+	case fails.WrongDuration:
+		return mycode.Verdict_time_limit_exceeded, fails, nil
+	case fails.WrongUsedMemory:
+		return mycode.Verdict_memory_limit_exceeded, fails, nil
+	case fails.WrongOutputSize:
+		return mycode.Verdict_output_limit_exceeded, fails, nil
+	case r.Verdict == mycode.Verdict_runtime_error:
+		return mycode.Verdict_runtime_error, fails, nil
+	case fails.WrongStdout:
+		return mycode.Verdict_wrong_answer, fails, nil
+	case fails.WrongChecker:
+		switch r.CheckerVerdict {
+		case mycode.CheckerVerdict_checker_wrong_answer:
+			return mycode.Verdict_wrong_answer, fails, nil
+		case mycode.CheckerVerdict_checker_presentation_error:
+			return mycode.Verdict_presentation_error, fails, nil
+		case mycode.CheckerVerdict_checker_partially:
+			return mycode.Verdict_partially_accepted, fails, nil
+		default:
+			return mycode.Verdict_internal_error, fails, nil
+		}
+	default:
+		return mycode.Verdict_accepted, fails, nil
+	}
+}
+
+// score gets test score from 0 to 1 for the judged run.
+func score(v mycode.Verdict, r *mycode.Run) float64 {
+	switch v {
+	case mycode.Verdict_accepted:
+		return 1
+	case mycode.Verdict_partially_accepted:
+		return r.Score
+	default:
+		return 0
+	}
+}
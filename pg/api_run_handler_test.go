@@ -0,0 +1,238 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestJudge(t *testing.T) {
+
+	simple := &mycode.Test{
+		Type:           mycode.TestType_simple,
+		MaxDuration:    "1s",
+		MaxMemory:      "64MB",
+		ExpectedStdout: "42\n",
+	}
+
+	checker := &mycode.Test{
+		Type:        mycode.TestType_checker,
+		MaxDuration: "1s",
+		MaxMemory:   "64MB",
+	}
+
+	tests := []struct {
+		name        string
+		test        *mycode.Test
+		run         *mycode.Run
+		wantVerdict mycode.Verdict
+		wantFails   *mycode.SolutionTestFails
+		wantErr     bool
+	}{{
+		name: "accepted",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB", Stdout: "42\n"},
+		wantVerdict: mycode.Verdict_accepted,
+		wantFails:   &mycode.SolutionTestFails{},
+	}, {
+		name:        "compilation error",
+		test:        simple,
+		run:         &mycode.Run{Verdict: mycode.Verdict_compilation_error},
+		wantVerdict: mycode.Verdict_compilation_error,
+		wantFails:   &mycode.SolutionTestFails{CompilationError: true},
+	}, {
+		name:        "internal error",
+		test:        simple,
+		run:         &mycode.Run{Verdict: mycode.Verdict_internal_error},
+		wantVerdict: mycode.Verdict_internal_error,
+		wantFails:   &mycode.SolutionTestFails{},
+	}, {
+		name:        "unknown verdict",
+		test:        simple,
+		run:         &mycode.Run{Verdict: mycode.Verdict_unknown_verdict},
+		wantVerdict: mycode.Verdict_internal_error,
+		wantFails:   &mycode.SolutionTestFails{},
+	}, {
+		name: "time limit before memory limit and wrong answer",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Duration: "2s", UsedMemory: "128MB", Stdout: "0\n"},
+		wantVerdict: mycode.Verdict_time_limit_exceeded,
+		wantFails: &mycode.SolutionTestFails{WrongDuration: true,
+			WrongUsedMemory: true, WrongStdout: true},
+	}, {
+		name: "time limit reported by runner",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_time_limit_exceeded,
+			Duration: "1s", UsedMemory: "0B"},
+		wantVerdict: mycode.Verdict_time_limit_exceeded,
+		wantFails: &mycode.SolutionTestFails{WrongDuration: true,
+			WrongStdout: true},
+	}, {
+		name: "memory limit before output limit",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_output_limit_exceeded,
+			Duration: "500ms", UsedMemory: "128MB", Stdout: "42\n"},
+		wantVerdict: mycode.Verdict_memory_limit_exceeded,
+		wantFails: &mycode.SolutionTestFails{WrongUsedMemory: true,
+			WrongOutputSize: true},
+	}, {
+		name: "memory limit reported by runner",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_memory_limit_exceeded,
+			Duration: "500ms", UsedMemory: "60MB"},
+		wantVerdict: mycode.Verdict_memory_limit_exceeded,
+		wantFails: &mycode.SolutionTestFails{WrongUsedMemory: true,
+			WrongStdout: true},
+	}, {
+		name: "output limit before wrong answer",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_output_limit_exceeded,
+			Duration: "500ms", UsedMemory: "32MB", Stdout: "4"},
+		wantVerdict: mycode.Verdict_output_limit_exceeded,
+		wantFails: &mycode.SolutionTestFails{WrongOutputSize: true,
+			WrongStdout: true},
+	}, {
+		name: "runtime error before wrong answer",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Duration: "500ms", UsedMemory: "32MB", Stdout: "4",
+			ExitCode: 1},
+		wantVerdict: mycode.Verdict_runtime_error,
+		wantFails:   &mycode.SolutionTestFails{WrongStdout: true},
+	}, {
+		name: "runtime error with right stdout",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Duration: "500ms", UsedMemory: "32MB", Stdout: "42\n",
+			Signal: 11},
+		wantVerdict: mycode.Verdict_runtime_error,
+		wantFails:   &mycode.SolutionTestFails{},
+	}, {
+		name: "wrong answer",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB", Stdout: "43\n"},
+		wantVerdict: mycode.Verdict_wrong_answer,
+		wantFails:   &mycode.SolutionTestFails{WrongStdout: true},
+	}, {
+		name: "checker ok",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_ok},
+		wantVerdict: mycode.Verdict_accepted,
+		wantFails:   &mycode.SolutionTestFails{},
+	}, {
+		name: "checker wrong answer",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_wrong_answer},
+		wantVerdict: mycode.Verdict_wrong_answer,
+		wantFails:   &mycode.SolutionTestFails{WrongChecker: true},
+	}, {
+		name: "checker presentation error",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_presentation_error},
+		wantVerdict: mycode.Verdict_presentation_error,
+		wantFails:   &mycode.SolutionTestFails{WrongChecker: true},
+	}, {
+		name: "checker partially",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_partially},
+		wantVerdict: mycode.Verdict_partially_accepted,
+		wantFails:   &mycode.SolutionTestFails{WrongChecker: true},
+	}, {
+		name: "checker fail",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_fail},
+		wantVerdict: mycode.Verdict_internal_error,
+		wantFails:   &mycode.SolutionTestFails{WrongChecker: true},
+	}, {
+		name: "runtime error before checker",
+		test: checker,
+		run: &mycode.Run{Verdict: mycode.Verdict_runtime_error,
+			Duration: "500ms", UsedMemory: "32MB",
+			CheckerVerdict: mycode.CheckerVerdict_checker_wrong_answer},
+		wantVerdict: mycode.Verdict_runtime_error,
+		wantFails:   &mycode.SolutionTestFails{WrongChecker: true},
+	}, {
+		name: "invalid run duration",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "long", UsedMemory: "32MB"},
+		wantErr: true,
+	}, {
+		name: "invalid used memory",
+		test: simple,
+		run: &mycode.Run{Verdict: mycode.Verdict_accepted,
+			Duration: "500ms", UsedMemory: "much"},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			verdict, fails, err := judge(tt.test, tt.run)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if verdict != tt.wantVerdict {
+				t.Errorf("verdict = %v, want %v", verdict, tt.wantVerdict)
+			}
+
+			if !reflect.DeepEqual(fails, tt.wantFails) {
+				t.Errorf("fails = %+v, want %+v", fails, tt.wantFails)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		verdict mycode.Verdict
+		run     *mycode.Run
+		want    float64
+	}{{
+		name:    "accepted",
+		verdict: mycode.Verdict_accepted,
+		run:     &mycode.Run{Score: 0.5},
+		want:    1,
+	}, {
+		name:    "partially accepted",
+		verdict: mycode.Verdict_partially_accepted,
+		run:     &mycode.Run{Score: 0.5},
+		want:    0.5,
+	}, {
+		name:    "wrong answer",
+		verdict: mycode.Verdict_wrong_answer,
+		run:     &mycode.Run{Score: 0.5},
+		want:    0,
+	}, {
+		name:    "time limit exceeded",
+		verdict: mycode.Verdict_time_limit_exceeded,
+		run:     &mycode.Run{},
+		want:    0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := score(tt.verdict, tt.run)
+			if got != tt.want {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dimuls/mycode"
+)
+
+// GetSimilarityReport returns pairs of similar solutions of the exercise
+// from the last similarity analysis ordered by score. Report is pending
+// when the exercise got solutions after the last analysis.
+func (api *MyCodeAPI) GetSimilarityReport(ctx context.Context,
+	req *mycode.GetSimilarityReportReq) (*mycode.GetSimilarityReportResp,
+	error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &mycode.GetSimilarityReportResp{}
+
+	err = api.db.QueryRowContext(ctx, `
+		select exists (
+			select 1 from solution as s
+			where s.exercise_id = e.id and s.id > e.similarity_solution_id
+		)
+		from exercise as e
+		where e.id = $1
+	`, req.ExerciseId).Scan(&resp.Pending)
+	if err != nil {
+		return nil, fmt.Errorf("get exercise from DB: %w", err)
+	}
+
+	rows, err := api.db.QueryContext(ctx, `
+		select sp.solution_a_id, sa.student_id, sp.solution_b_id,
+			sb.student_id, sp.score, sp.fragments
+		from similarity_pair as sp
+		join solution as sa on sp.solution_a_id = sa.id
+		join solution as sb on sp.solution_b_id = sb.id
+		where sp.exercise_id = $1 and sp.score >= $2
+		order by sp.score desc
+	`, req.ExerciseId, req.MinScore)
+	if err != nil {
+		return nil, fmt.Errorf("get similarity pairs from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			p             = &mycode.SimilarityPair{}
+			fragmentsJSON string
+		)
+		err = rows.Scan(&p.SolutionAId, &p.StudentAId, &p.SolutionBId,
+			&p.StudentBId, &p.Score, &fragmentsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("get similarity pair row from DB: %w",
+				err)
+		}
+
+		err = json.Unmarshal([]byte(fragmentsJSON), &p.Fragments)
+		if err != nil {
+			return nil, fmt.Errorf("JSON unmarshal fragments: %w", err)
+		}
+
+		resp.Pairs = append(resp.Pairs, p)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("similarity pairs rows error: %w", rows.Err())
+	}
+
+	return resp, nil
+}
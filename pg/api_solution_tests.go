@@ -104,25 +104,20 @@ func (api *MyCodeAPI) GetSolutionTests(ctx context.Context,
 		return nil, fmt.Errorf("unexpected user role: %s", ur)
 	}
 
-	var sts []*mycode.SolutionTest
-
 	var rows *sql.Rows
 
 	if req.SolutionId != 0 {
 		rows, err = api.db.QueryContext(ctx, `
-			select id, solution_id, test_id, status, duration, used_memory,
-				   stdout, stderr, checker_stdout, checker_stderr, fails
-			from solution_test
-			where solution_id = $1
+			select `+solutionTestColumns+`
+			from solution_test as st
+			where st.solution_id = $1
 		`, req.SolutionId)
 		if err != nil {
 			return nil, fmt.Errorf("get solution tests from DB: %w", err)
 		}
 	} else {
 		rows, err = api.db.QueryContext(ctx, `
-			select st.id, st.solution_id, st.test_id, st.status, st.duration,
-				   st.used_memory, st.stdout, st.stderr,
-				   st.checker_stdout, st.checker_stderr, st.fails
+			select `+solutionTestColumns+`
 			from solution_test as st
 			join solution s on s.id = st.solution_id
 			where s.student_id = $1
@@ -132,53 +127,125 @@ func (api *MyCodeAPI) GetSolutionTests(ctx context.Context,
 		}
 	}
 
+	defer rows.Close()
+
+	var sts []*mycode.SolutionTest
+
 	for rows.Next() {
-		var (
-			st                                   = &mycode.SolutionTest{}
-			duration, usedMemory, stdout, stderr sql.NullString
-			checkerStdout, checkerStderr         sql.NullString
-			failsJSON                            []byte
-		)
-		err = rows.Scan(&st.Id, &st.SolutionId, &st.TestId, &st.Status,
-			&duration, &usedMemory, &stdout, &stderr,
-			&checkerStdout, &checkerStderr, &failsJSON)
-
-		if duration.Valid {
-			st.Duration = duration.String
+		st, err := api.scanSolutionTest(ctx, rows)
+		if err != nil {
+			return nil, err
 		}
+		sts = append(sts, st)
+	}
 
-		if usedMemory.Valid {
-			st.UsedMemory = usedMemory.String
-		}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("solution tests rows error: %w", rows.Err())
+	}
 
-		if stdout.Valid {
-			st.Stdout = stdout.String
-		}
+	return &mycode.GetSolutionTestsResp{SolutionTests: sts}, nil
+}
 
-		if stderr.Valid {
-			st.Stderr = stderr.String
-		}
+// solutionTestColumns are columns of solution test st scanned by
+// scanSolutionTest.
+const solutionTestColumns = `st.id, st.solution_id, st.test_id, st.status,
+	st.duration, st.used_memory, st.stdout, st.stderr, st.checker_stdout,
+	st.checker_stderr, st.fails, st.compiler_output, st.verdict,
+	st.exit_code, st.signal, st.wall_duration, st.score, st.checker_message,
+	st.stdout_blob, st.stderr_blob`
 
-		if checkerStdout.Valid {
-			st.CheckerStdout = checkerStdout.String
-		}
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-		if checkerStderr.Valid {
-			st.CheckerStderr = checkerStderr.String
-		}
+// scanSolutionTest scans solution test fetching its outputs from the blob
+// storage.
+func (api *MyCodeAPI) scanSolutionTest(ctx context.Context,
+	row rowScanner) (*mycode.SolutionTest, error) {
+
+	var (
+		st                                   = &mycode.SolutionTest{}
+		duration, usedMemory, stdout, stderr sql.NullString
+		checkerStdout, checkerStderr         sql.NullString
+		compilerOutput, wallDuration         sql.NullString
+		checkerMessage                       sql.NullString
+		stdoutBlob, stderrBlob               sql.NullString
+		score                                sql.NullFloat64
+		failsJSON                            []byte
+		verdict, exitCode, signal            sql.NullInt32
+	)
+
+	err := row.Scan(&st.Id, &st.SolutionId, &st.TestId, &st.Status,
+		&duration, &usedMemory, &stdout, &stderr,
+		&checkerStdout, &checkerStderr, &failsJSON, &compilerOutput,
+		&verdict, &exitCode, &signal, &wallDuration, &score,
+		&checkerMessage, &stdoutBlob, &stderrBlob)
+	if err != nil {
+		return nil, fmt.Errorf("get solution test row from DB: %w", err)
+	}
 
-		st.Fails = &mycode.SolutionTestFails{}
+	if duration.Valid {
+		st.Duration = duration.String
+	}
 
-		if failsJSON != nil {
-			err := json.Unmarshal(failsJSON, st.Fails)
-			if err != nil {
-				return nil, fmt.Errorf("JSON unmarshal fails: %w", err)
-			}
+	if wallDuration.Valid {
+		st.WallDuration = wallDuration.String
+	}
 
-		}
+	if usedMemory.Valid {
+		st.UsedMemory = usedMemory.String
+	}
 
-		sts = append(sts, st)
+	st.Stdout, err = api.getContent(ctx, stdout.String, stdoutBlob)
+	if err != nil {
+		return nil, fmt.Errorf("get solution test stdout: %w", err)
 	}
 
-	return &mycode.GetSolutionTestsResp{SolutionTests: sts}, nil
+	st.Stderr, err = api.getContent(ctx, stderr.String, stderrBlob)
+	if err != nil {
+		return nil, fmt.Errorf("get solution test stderr: %w", err)
+	}
+
+	if checkerStdout.Valid {
+		st.CheckerStdout = checkerStdout.String
+	}
+
+	if checkerStderr.Valid {
+		st.CheckerStderr = checkerStderr.String
+	}
+
+	if checkerMessage.Valid {
+		st.CheckerMessage = checkerMessage.String
+	}
+
+	if score.Valid {
+		st.Score = score.Float64
+	}
+
+	if compilerOutput.Valid {
+		st.CompilerOutput = compilerOutput.String
+	}
+
+	if verdict.Valid {
+		st.Verdict = mycode.Verdict(verdict.Int32)
+	}
+
+	if exitCode.Valid {
+		st.ExitCode = exitCode.Int32
+	}
+
+	if signal.Valid {
+		st.Signal = signal.Int32
+	}
+
+	st.Fails = &mycode.SolutionTestFails{}
+
+	if failsJSON != nil {
+		err := json.Unmarshal(failsJSON, st.Fails)
+		if err != nil {
+			return nil, fmt.Errorf("JSON unmarshal fails: %w", err)
+		}
+	}
+
+	return st, nil
 }
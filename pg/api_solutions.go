@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dimuls/mycode"
 )
@@ -34,6 +35,96 @@ func (api *MyCodeAPI) checkExerciseBelongsToStudent(ctx context.Context,
 	return nil
 }
 
+// checkExerciseOpen checks that the exercise assigned to the student is
+// open for submissions. Submission after the exercise closes is rejected
+// or accepted as late depending on the assignment late policy.
+func (api *MyCodeAPI) checkExerciseOpen(ctx context.Context,
+	exerciseID, studentID int64) (late bool, err error) {
+
+	var (
+		opened, closed bool
+		latePolicy     mycode.LatePolicy
+	)
+
+	err = api.db.QueryRowContext(ctx, `
+		select coalesce(opens_at <= now(), true),
+			coalesce(closes_at < now(), false), late_policy
+		from student_exercise
+		where exercise_id = $1 and student_id = $2
+	`, exerciseID, studentID).Scan(&opened, &closed, &latePolicy)
+	if err != nil {
+		return false, fmt.Errorf("get exercise window from DB: %w", err)
+	}
+
+	if !opened {
+		return false, fmt.Errorf("exercise is not opened yet")
+	}
+
+	if closed {
+		if latePolicy == mycode.LatePolicy_reject_late {
+			return false, fmt.Errorf("exercise is closed")
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// checkSubmissionLimits checks the exercise max attempts and submission
+// cooldown. Student exercise row is locked, so concurrent submissions are
+// counted one after another.
+func checkSubmissionLimits(ctx context.Context, tx *sql.Tx,
+	exerciseID, studentID int64) error {
+
+	_, err := tx.ExecContext(ctx, `
+		select 1 from student_exercise
+		where exercise_id = $1 and student_id = $2
+		for update
+	`, exerciseID, studentID)
+	if err != nil {
+		return fmt.Errorf("lock student exercise in DB: %w", err)
+	}
+
+	var (
+		maxAttempts, attempts int64
+		submissionCooldown    sql.NullString
+		sinceLast             sql.NullFloat64
+	)
+
+	err = tx.QueryRowContext(ctx, `
+		select e.max_attempts, e.submission_cooldown, count(s.id),
+			extract(epoch from now() - max(s.created_at))
+		from exercise as e
+		left join solution as s on s.exercise_id = e.id
+			and s.student_id = $2
+		where e.id = $1
+		group by e.id
+	`, exerciseID, studentID).Scan(&maxAttempts, &submissionCooldown,
+		&attempts, &sinceLast)
+	if err != nil {
+		return fmt.Errorf("get submission limits from DB: %w", err)
+	}
+
+	if maxAttempts > 0 && attempts >= maxAttempts {
+		return fmt.Errorf("max attempts count %d reached", maxAttempts)
+	}
+
+	if submissionCooldown.Valid && sinceLast.Valid {
+		cooldown, err := time.ParseDuration(submissionCooldown.String)
+		if err != nil {
+			return fmt.Errorf("parse submission cooldown: %w", err)
+		}
+
+		wait := cooldown - time.Duration(sinceLast.Float64*float64(time.Second))
+		if wait > 0 {
+			return fmt.Errorf("next submission is allowed in %s",
+				wait.Round(time.Second))
+		}
+	}
+
+	return nil
+}
+
 func (api *MyCodeAPI) AddSolution(ctx context.Context,
 	req *mycode.AddSolutionReq) (resp *mycode.AddSolutionResp, err error) {
 
@@ -55,6 +146,36 @@ func (api *MyCodeAPI) AddSolution(ctx context.Context,
 		return nil, err
 	}
 
+	late, err := api.checkExerciseOpen(ctx, req.ExerciseId, s.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending bool
+
+	err = api.db.QueryRowContext(ctx, `
+		select exists (
+			select 1 from test
+			where exercise_id = $1 and expected_stdout_pending
+		)
+	`, req.ExerciseId).Scan(&pending)
+	if err != nil {
+		return nil, fmt.Errorf("check expected stdouts pending: %w", err)
+	}
+
+	if pending {
+		return nil, fmt.Errorf("exercise expected stdouts are being generated")
+	}
+
+	busy, err := api.codesLimiter.busy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check runners busy: %w", err)
+	}
+
+	if busy {
+		return nil, fmt.Errorf("runners are busy, try again later")
+	}
+
 	tx, err := api.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin tx: %w", err)
@@ -69,13 +190,18 @@ func (api *MyCodeAPI) AddSolution(ctx context.Context,
 		}
 	}()
 
+	err = checkSubmissionLimits(ctx, tx, req.ExerciseId, s.Id)
+	if err != nil {
+		return nil, err
+	}
+
 	var solutionID int64
 
 	err = tx.QueryRowContext(ctx, `
-		insert into solution (student_id, exercise_id, source)
-		values ($1, $2, $3)
+		insert into solution (student_id, exercise_id, source, late)
+		values ($1, $2, $3, $4)
 		returning id
-	`, s.Id, req.ExerciseId, req.Source).Scan(&solutionID)
+	`, s.Id, req.ExerciseId, req.Source, late).Scan(&solutionID)
 	if err != nil {
 		return nil, fmt.Errorf("add solution to DB: %w", err)
 	}
@@ -99,7 +225,9 @@ func (api *MyCodeAPI) AddSolution(ctx context.Context,
 
 	rows, err := tx.Query(`
 				select st.id, e.language, s.source, t.type,
-					t.stdin, t.checker_language, t.checker_source
+					t.stdin, t.checker_language, t.checker_source,
+					t.max_duration, t.max_memory, t.expected_stdout,
+					t.stdin_blob, t.expected_stdout_blob, t.checker_protocol
                 from solution_test as st
                 join test t on st.test_id = t.id
                 join solution s on st.solution_id = s.id
@@ -107,42 +235,67 @@ func (api *MyCodeAPI) AddSolution(ctx context.Context,
                 where st.solution_id = $1
         `, solutionID)
 	if err != nil {
-		api.log.WithError(err).Error("failed to get solutions from DB")
+		return nil, fmt.Errorf("get solution tests from DB: %w", err)
 	}
 
+	var cs []*mycode.Code
+
 	for rows.Next() {
 		var (
-			solutionTestID  int64
-			language        mycode.Language
-			source          string
-			testType        mycode.TestType
-			stdin           string
-			checkerLanguage sql.NullInt32
-			checkerSource   sql.NullString
+			solutionTestID     int64
+			language           mycode.Language
+			source             string
+			testType           mycode.TestType
+			stdin              string
+			checkerLanguage    sql.NullInt32
+			checkerSource      sql.NullString
+			maxDuration        string
+			maxMemory          string
+			expectedStdout     sql.NullString
+			stdinBlob          sql.NullString
+			expectedStdoutBlob sql.NullString
+			checkerProtocol    mycode.CheckerProtocol
 		)
 		err := rows.Scan(&solutionTestID, &language, &source, &testType,
-			&stdin, &checkerLanguage, &checkerSource)
+			&stdin, &checkerLanguage, &checkerSource, &maxDuration,
+			&maxMemory, &expectedStdout, &stdinBlob, &expectedStdoutBlob,
+			&checkerProtocol)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"failed to get solution row from DB: %w", err)
 		}
 
-		err = api.codePublisher.PublishCode(&mycode.Code{
+		c := &mycode.Code{
 			SolutionTestId:  solutionTestID,
 			Language:        language,
 			Source:          source,
 			Stdin:           stdin,
+			StdinBlob:       stdinBlob.String,
 			CheckerLanguage: mycode.Language(checkerLanguage.Int32),
 			CheckerSource:   checkerSource.String,
 			WithChecker:     testType == mycode.TestType_checker,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to publish code: %w", err)
+			Interactive:     testType == mycode.TestType_interactive,
+			MaxDuration:     maxDuration,
+			MaxMemory:       maxMemory,
+			CheckerProtocol: checkerProtocol,
+		}
+
+		if c.WithChecker || c.Interactive {
+			c.ExpectedStdout = expectedStdout.String
+			c.ExpectedStdoutBlob = expectedStdoutBlob.String
 		}
+
+		cs = append(cs, c)
 	}
 
 	if rows.Err() != nil {
-		api.log.WithError(rows.Err()).Error("got solutions test rows error")
+		err = rows.Err()
+		return nil, fmt.Errorf("solution tests rows error: %w", err)
+	}
+
+	err = enqueueCodes(ctx, tx, cs)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue codes: %w", err)
 	}
 
 	err = tx.Commit()
@@ -150,6 +303,8 @@ func (api *MyCodeAPI) AddSolution(ctx context.Context,
 		return nil, fmt.Errorf("commit changes to DB: %w", err)
 	}
 
+	api.notifyOutbox()
+
 	return &mycode.AddSolutionResp{SolutionId: solutionID}, nil
 }
 
@@ -210,7 +365,7 @@ func (api *MyCodeAPI) GetSolutions(ctx context.Context,
 	}
 
 	rows, err := api.db.QueryContext(ctx, fmt.Sprintf(`
-			select id, student_id, exercise_id, source 
+			select id, student_id, exercise_id, source, grade, late
 			from solution
 			where %s
 		`, strings.Join(wheres, " and ")), args...)
@@ -221,12 +376,20 @@ func (api *MyCodeAPI) GetSolutions(ctx context.Context,
 	var ss []*mycode.Solution
 
 	for rows.Next() {
-		s := &mycode.Solution{}
-		err = rows.Scan(&s.Id, &s.StudentId, &s.ExerciseId, &s.Source)
+		var (
+			s     = &mycode.Solution{}
+			grade sql.NullFloat64
+		)
+		err = rows.Scan(&s.Id, &s.StudentId, &s.ExerciseId, &s.Source,
+			&grade, &s.Late)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"get solution row from DB: %w", err)
 		}
+		if grade.Valid {
+			s.Grade = grade.Float64
+			s.Graded = true
+		}
 		ss = append(ss, s)
 	}
 
@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"github.com/c2h5oh/datasize"
+	"github.com/lib/pq"
 
 	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/archive"
 )
 
 func parseBytes(s string) (datasize.ByteSize, error) {
@@ -38,6 +40,29 @@ func (api *MyCodeAPI) AddTest(ctx context.Context,
 		return nil, fmt.Errorf("parse max_memory: %w", err)
 	}
 
+	if _, exists := mycode.ComparisonMode_name[int32(req.ComparisonMode)]; !exists {
+		return nil, fmt.Errorf("invalid comparison_mode")
+	}
+
+	if req.FloatAbsEpsilon < 0 || req.FloatRelEpsilon < 0 {
+		return nil, fmt.Errorf("negative float epsilon")
+	}
+
+	if _, exists := mycode.CheckerProtocol_name[int32(req.CheckerProtocol)]; !exists {
+		return nil, fmt.Errorf("invalid checker_protocol")
+	}
+
+	if req.GenerateExpectedStdout {
+		if req.ExpectedStdout != "" {
+			return nil, fmt.Errorf("both expected_stdout and " +
+				"generate_expected_stdout are set")
+		}
+		if req.Type == mycode.TestType_interactive {
+			return nil, fmt.Errorf("interactive test expected stdout " +
+				"can't be generated")
+		}
+	}
+
 	t, err := api.teacherFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get teacher from context: %w", err)
@@ -58,6 +83,24 @@ func (api *MyCodeAPI) AddTest(ctx context.Context,
 		return nil, fmt.Errorf("test exercise doesn't belongs to teacher")
 	}
 
+	if req.GenerateExpectedStdout {
+		err = api.checkExerciseHasReference(ctx, req.ExerciseId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stdin, stdinBlob, err := api.putContent(ctx, req.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("put stdin: %w", err)
+	}
+
+	expectedStdout, expectedStdoutBlob, err := api.putContent(ctx,
+		req.ExpectedStdout)
+	if err != nil {
+		return nil, fmt.Errorf("put expected stdout: %w", err)
+	}
+
 	var id int64
 
 	switch req.Type {
@@ -65,21 +108,29 @@ func (api *MyCodeAPI) AddTest(ctx context.Context,
 		err = api.db.QueryRowContext(ctx, `
 			insert into test (
 				exercise_id, type, name, max_duration, max_memory, stdin,
-				expected_stdout, checker_language, checker_source)
-			values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				expected_stdout, checker_language, checker_source,
+				comparison_mode, float_abs_epsilon, float_rel_epsilon,
+				stdin_blob, expected_stdout_blob)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+				$14)
 			returning id
 		`, req.ExerciseId, req.Type, req.Name, req.MaxDuration, req.MaxMemory,
-			req.Stdin, req.ExpectedStdout, nil, nil).
+			stdin, expectedStdout, nil, nil, req.ComparisonMode,
+			req.FloatAbsEpsilon, req.FloatRelEpsilon, stdinBlob,
+			expectedStdoutBlob).
 			Scan(&id)
-	case mycode.TestType_checker:
+	case mycode.TestType_checker, mycode.TestType_interactive:
 		err = api.db.QueryRowContext(ctx, `
 			insert into test (
 				exercise_id, type, name, max_duration, max_memory, stdin,
-				expected_stdout, checker_language, checker_source)
-			values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				expected_stdout, checker_language, checker_source,
+				stdin_blob, expected_stdout_blob, checker_protocol)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 			returning id
 		`, req.ExerciseId, req.Type, req.Name, req.MaxDuration, req.MaxMemory,
-			req.Stdin, nil, req.CheckerLanguage, req.CheckerSource).
+			stdin, expectedStdout, req.CheckerLanguage,
+			req.CheckerSource, stdinBlob, expectedStdoutBlob,
+			req.CheckerProtocol).
 			Scan(&id)
 	default:
 		return nil, fmt.Errorf("invalid type")
@@ -88,6 +139,13 @@ func (api *MyCodeAPI) AddTest(ctx context.Context,
 		return nil, fmt.Errorf("add test to DB: %w", err)
 	}
 
+	if req.GenerateExpectedStdout {
+		err = api.generateExpectedStdouts(ctx, 0, id)
+		if err != nil {
+			return nil, fmt.Errorf("generate expected stdout: %w", err)
+		}
+	}
+
 	return &mycode.AddTestResp{TestId: id}, nil
 }
 
@@ -132,15 +190,33 @@ func (api *MyCodeAPI) EditTest(ctx context.Context,
 		return nil, err
 	}
 
-	var testType mycode.TestType
+	var (
+		testType   mycode.TestType
+		exerciseID int64
+	)
 
 	err = api.db.QueryRowContext(ctx, `
-		select type from test where id = $1
-	`, req.TestId).Scan(&testType)
+		select type, exercise_id from test where id = $1
+	`, req.TestId).Scan(&testType, &exerciseID)
 	if err != nil {
 		return nil, fmt.Errorf("geet test type: %w", err)
 	}
 
+	if req.GenerateExpectedStdout {
+		if req.ExpectedStdoutSet {
+			return nil, fmt.Errorf("both expected_stdout and " +
+				"generate_expected_stdout are set")
+		}
+		if testType == mycode.TestType_interactive {
+			return nil, fmt.Errorf("interactive test expected stdout " +
+				"can't be generated")
+		}
+		err = api.checkExerciseHasReference(ctx, exerciseID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var (
 		args []interface{}
 		sets []string
@@ -170,17 +246,53 @@ func (api *MyCodeAPI) EditTest(ctx context.Context,
 	}
 
 	if req.StdinSet {
-		args = append(args, req.Stdin)
+		stdin, stdinBlob, err := api.putContent(ctx, req.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("put stdin: %w", err)
+		}
+		args = append(args, stdin)
 		sets = append(sets, fmt.Sprintf("stdin = $%d", len(args)))
+		args = append(args, stdinBlob)
+		sets = append(sets, fmt.Sprintf("stdin_blob = $%d", len(args)))
+	}
+
+	if req.ExpectedStdoutSet {
+		expectedStdout, expectedStdoutBlob, err := api.putContent(ctx,
+			req.ExpectedStdout)
+		if err != nil {
+			return nil, fmt.Errorf("put expected stdout: %w", err)
+		}
+		args = append(args, expectedStdout)
+		sets = append(sets, fmt.Sprintf("expected_stdout = $%d", len(args)))
+		args = append(args, expectedStdoutBlob)
+		sets = append(sets, fmt.Sprintf("expected_stdout_blob = $%d",
+			len(args)))
 	}
 
 	switch testType {
 	case mycode.TestType_simple:
-		if req.ExpectedStdoutSet {
-			args = append(args, req.ExpectedStdout)
-			sets = append(sets, fmt.Sprintf("expected_stdout = $%d", len(args)))
+		if req.ComparisonModeSet {
+			if _, exists := mycode.ComparisonMode_name[int32(req.ComparisonMode)]; !exists {
+				return nil, fmt.Errorf("invalid comparison_mode")
+			}
+			args = append(args, req.ComparisonMode)
+			sets = append(sets, fmt.Sprintf("comparison_mode = $%d", len(args)))
+		}
+		if req.FloatAbsEpsilonSet {
+			if req.FloatAbsEpsilon < 0 {
+				return nil, fmt.Errorf("negative float_abs_epsilon")
+			}
+			args = append(args, req.FloatAbsEpsilon)
+			sets = append(sets, fmt.Sprintf("float_abs_epsilon = $%d", len(args)))
 		}
-	case mycode.TestType_checker:
+		if req.FloatRelEpsilonSet {
+			if req.FloatRelEpsilon < 0 {
+				return nil, fmt.Errorf("negative float_rel_epsilon")
+			}
+			args = append(args, req.FloatRelEpsilon)
+			sets = append(sets, fmt.Sprintf("float_rel_epsilon = $%d", len(args)))
+		}
+	case mycode.TestType_checker, mycode.TestType_interactive:
 		if req.CheckerLanguageSet {
 			args = append(args, req.CheckerLanguage)
 			sets = append(sets, fmt.Sprintf("checker_language = $%d", len(args)))
@@ -189,19 +301,35 @@ func (api *MyCodeAPI) EditTest(ctx context.Context,
 			args = append(args, req.CheckerSource)
 			sets = append(sets, fmt.Sprintf("checker_source = $%d", len(args)))
 		}
+		if req.CheckerProtocolSet {
+			if _, exists := mycode.CheckerProtocol_name[int32(req.CheckerProtocol)]; !exists {
+				return nil, fmt.Errorf("invalid checker_protocol")
+			}
+			args = append(args, req.CheckerProtocol)
+			sets = append(sets, fmt.Sprintf("checker_protocol = $%d", len(args)))
+		}
 	}
 
-	if len(sets) == 0 {
+	if len(sets) == 0 && !req.GenerateExpectedStdout {
 		return nil, fmt.Errorf("nothing changed")
 	}
 
-	args = append(args, req.TestId)
+	if len(sets) != 0 {
+		args = append(args, req.TestId)
 
-	_, err = api.db.ExecContext(ctx, fmt.Sprintf(`
-		update test set %s where id = $%d
-	`, strings.Join(sets, ", "), len(args)), args...)
-	if err != nil {
-		return nil, fmt.Errorf("update test in DB: %w", err)
+		_, err = api.db.ExecContext(ctx, fmt.Sprintf(`
+			update test set %s where id = $%d
+		`, strings.Join(sets, ", "), len(args)), args...)
+		if err != nil {
+			return nil, fmt.Errorf("update test in DB: %w", err)
+		}
+	}
+
+	if req.GenerateExpectedStdout {
+		err = api.generateExpectedStdouts(ctx, 0, req.TestId)
+		if err != nil {
+			return nil, fmt.Errorf("generate expected stdout: %w", err)
+		}
 	}
 
 	return &mycode.EditTestResp{}, nil
@@ -234,6 +362,232 @@ func (api *MyCodeAPI) RemoveTest(ctx context.Context,
 	return &mycode.RemoveTestResp{}, nil
 }
 
+// UploadTests adds tests from zip archive of 01.in and 01.out file pairs
+// with the same limits and comparison mode. If replace is set exercise tests
+// with the same names are updated, others are removed and the exercise
+// solutions are rejudged.
+func (api *MyCodeAPI) UploadTests(ctx context.Context,
+	req *mycode.UploadTestsReq) (*mycode.UploadTestsResp, error) {
+
+	if req.ExerciseId == 0 {
+		return nil, fmt.Errorf("empty exercise_id")
+	}
+
+	if len(req.Archive) == 0 {
+		return nil, fmt.Errorf("empty archive")
+	}
+
+	_, err := time.ParseDuration(req.MaxDuration)
+	if err != nil {
+		return nil, fmt.Errorf("parse max_duration: %w", err)
+	}
+
+	_, err = parseBytes(req.MaxMemory)
+	if err != nil {
+		return nil, fmt.Errorf("parse max_memory: %w", err)
+	}
+
+	if _, exists := mycode.ComparisonMode_name[int32(req.ComparisonMode)]; !exists {
+		return nil, fmt.Errorf("invalid comparison_mode")
+	}
+
+	t, err := api.teacherFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get teacher from context: %w", err)
+	}
+
+	err = api.checkExerciseBelongsToTeacher(ctx, req.ExerciseId, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := archive.ReadTests(req.Archive)
+	if err != nil {
+		return nil, fmt.Errorf("read tests archive: %w", err)
+	}
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	// Replaced tests are matched by name and updated in place, so their
+	// solution tests results aren't lost.
+	replaced := map[string]int64{}
+
+	if req.Replace {
+		replaced, err = exerciseTestIDs(ctx, tx, req.ExerciseId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		ids             []int64
+		generateTestIDs []int64
+	)
+
+	for _, test := range ts {
+		var (
+			id                 int64
+			stdin              string
+			stdinBlob          sql.NullString
+			expectedStdout     string
+			expectedStdoutBlob sql.NullString
+		)
+
+		stdin, stdinBlob, err = api.putContent(ctx, test.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("put test %s stdin: %w", test.Name, err)
+		}
+
+		expectedStdout, expectedStdoutBlob, err = api.putContent(ctx,
+			test.ExpectedStdout)
+		if err != nil {
+			return nil, fmt.Errorf("put test %s expected stdout: %w",
+				test.Name, err)
+		}
+
+		id, matched := replaced[test.Name]
+
+		if matched {
+			// Attempt increment makes pending reference run of the test
+			// outdated.
+			_, err = tx.ExecContext(ctx, `
+				update test set type = $2, max_duration = $3,
+					max_memory = $4, stdin = $5, expected_stdout = $6,
+					comparison_mode = $7, stdin_blob = $8,
+					expected_stdout_blob = $9, checker_language = default,
+					checker_source = default, checker_protocol = default,
+					float_abs_epsilon = default, float_rel_epsilon = default,
+					expected_stdout_pending = false,
+					expected_stdout_attempt = expected_stdout_attempt + 1,
+					expected_stdout_error = null
+				where id = $1
+			`, id, mycode.TestType_simple, req.MaxDuration, req.MaxMemory,
+				stdin, expectedStdout, req.ComparisonMode, stdinBlob,
+				expectedStdoutBlob)
+			if err != nil {
+				return nil, fmt.Errorf("update test %s in DB: %w",
+					test.Name, err)
+			}
+		} else {
+			err = tx.QueryRowContext(ctx, `
+				insert into test (
+					exercise_id, type, name, max_duration, max_memory, stdin,
+					expected_stdout, comparison_mode, stdin_blob,
+					expected_stdout_blob)
+				values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				returning id
+			`, req.ExerciseId, mycode.TestType_simple, test.Name,
+				req.MaxDuration, req.MaxMemory, stdin, expectedStdout,
+				req.ComparisonMode, stdinBlob, expectedStdoutBlob).Scan(&id)
+			if err != nil {
+				return nil, fmt.Errorf("add test %s to DB: %w", test.Name, err)
+			}
+		}
+
+		ids = append(ids, id)
+
+		if test.ExpectedStdout == "" {
+			generateTestIDs = append(generateTestIDs, id)
+		}
+	}
+
+	if req.Replace {
+		_, err = tx.ExecContext(ctx, `
+			delete from test where exercise_id = $1 and id <> all($2)
+		`, req.ExerciseId, pq.Array(ids))
+		if err != nil {
+			return nil, fmt.Errorf("delete tests from DB: %w", err)
+		}
+
+		var hasSolutions bool
+
+		err = tx.QueryRowContext(ctx, `
+			select exists (select 1 from solution where exercise_id = $1)
+		`, req.ExerciseId).Scan(&hasSolutions)
+		if err != nil {
+			return nil, fmt.Errorf("check exercise solutions exist: %w", err)
+		}
+
+		// Solutions are rejudged with the new tests, which can't be done
+		// until expected stdouts are generated.
+		if hasSolutions {
+			if len(generateTestIDs) != 0 {
+				err = fmt.Errorf("tests of exercise with solutions can't " +
+					"be replaced with tests without expected stdouts")
+				return nil, err
+			}
+
+			_, err = resetSolutionTests(ctx, tx, "s.exercise_id = $1",
+				req.ExerciseId)
+			if err != nil {
+				return nil, fmt.Errorf("rejudge: %w", err)
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	api.notifyOutbox()
+
+	for _, id := range generateTestIDs {
+		err = api.generateExpectedStdouts(ctx, 0, id)
+		if err != nil {
+			return nil, fmt.Errorf("generate expected stdout: %w", err)
+		}
+	}
+
+	return &mycode.UploadTestsResp{TestIds: ids}, nil
+}
+
+// exerciseTestIDs returns exercise tests IDs by names.
+func exerciseTestIDs(ctx context.Context, tx *sql.Tx,
+	exerciseID int64) (map[string]int64, error) {
+
+	rows, err := tx.QueryContext(ctx, `
+		select id, name from test where exercise_id = $1 for update
+	`, exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf("get tests from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	ids := map[string]int64{}
+
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return nil, fmt.Errorf("get test row from DB: %w", err)
+		}
+		ids[name] = id
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("tests rows error: %w", rows.Err())
+	}
+
+	return ids, nil
+}
+
 func (api *MyCodeAPI) GetTests(ctx context.Context,
 	req *mycode.GetTestsReq) (*mycode.GetTestsResp, error) {
 
@@ -291,7 +645,10 @@ func (api *MyCodeAPI) GetTests(ctx context.Context,
 	if req.ExerciseId != 0 {
 		rows, err = api.db.QueryContext(ctx, `
 			select id, exercise_id, type, name, max_duration, max_memory, stdin,
-				expected_stdout, checker_language, checker_source
+				expected_stdout, checker_language, checker_source,
+				comparison_mode, float_abs_epsilon, float_rel_epsilon,
+				expected_stdout_pending, expected_stdout_error, stdin_blob,
+				expected_stdout_blob, checker_protocol
 			from test
 			where exercise_id = $1
 		`, req.ExerciseId)
@@ -299,7 +656,10 @@ func (api *MyCodeAPI) GetTests(ctx context.Context,
 		rows, err = api.db.QueryContext(ctx, `
 			select t.id, t.exercise_id, t.type, t.name, t.max_duration,
 				t.max_memory, t.stdin, t.expected_stdout, t.checker_language,
-				t.checker_source
+				t.checker_source, t.comparison_mode, t.float_abs_epsilon,
+				t.float_rel_epsilon, t.expected_stdout_pending,
+				t.expected_stdout_error, t.stdin_blob, t.expected_stdout_blob,
+				t.checker_protocol
 			from test as t
 			join exercise e on t.exercise_id = e.id
 			join student_exercise se on e.id = se.exercise_id
@@ -317,20 +677,33 @@ func (api *MyCodeAPI) GetTests(ctx context.Context,
 		t := &mycode.Test{}
 
 		var (
-			expectedStdout  sql.NullString
-			checkerLanguage sql.NullInt32
-			checkerSource   sql.NullString
+			expectedStdout     sql.NullString
+			checkerLanguage    sql.NullInt32
+			checkerSource      sql.NullString
+			expectedError      sql.NullString
+			stdinBlob          sql.NullString
+			expectedStdoutBlob sql.NullString
 		)
 
 		err := rows.Scan(&t.Id, &t.ExerciseId, &t.Type, &t.Name,
 			&t.MaxDuration, &t.MaxMemory, &t.Stdin, &expectedStdout,
-			&checkerLanguage, &checkerSource)
+			&checkerLanguage, &checkerSource, &t.ComparisonMode,
+			&t.FloatAbsEpsilon, &t.FloatRelEpsilon, &t.ExpectedStdoutPending,
+			&expectedError, &stdinBlob, &expectedStdoutBlob,
+			&t.CheckerProtocol)
 		if err != nil {
 			return nil, fmt.Errorf("get test row from DB: %w", err)
 		}
 
-		if expectedStdout.Valid {
-			t.ExpectedStdout = expectedStdout.String
+		t.Stdin, err = api.getContent(ctx, t.Stdin, stdinBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get test stdin: %w", err)
+		}
+
+		t.ExpectedStdout, err = api.getContent(ctx, expectedStdout.String,
+			expectedStdoutBlob)
+		if err != nil {
+			return nil, fmt.Errorf("get test expected stdout: %w", err)
 		}
 
 		if checkerLanguage.Valid {
@@ -341,6 +714,10 @@ func (api *MyCodeAPI) GetTests(ctx context.Context,
 			t.CheckerSource = checkerSource.String
 		}
 
+		if expectedError.Valid {
+			t.ExpectedStdoutError = expectedError.String
+		}
+
 		ts = append(ts, t)
 	}
 
@@ -0,0 +1,146 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	blobSweepInterval = time.Hour
+
+	// blobSweepGrace is the time after the blob put when it can be deleted
+	// if unreferenced. Blobs are put before the rows referencing them are
+	// committed, and playground runs and outbox codes reference them only
+	// until they are handled.
+	blobSweepGrace = 24 * time.Hour
+
+	// blobSweepBatch is the max count of blobs checked for references at
+	// once.
+	blobSweepBatch = 1000
+)
+
+// errSweepStopped stops blobs walk when API is closed.
+var errSweepStopped = errors.New("sweep stopped")
+
+// runBlobSweeper periodically deletes blobs which aren't referenced by tests
+// and solution tests, like replaced tests contents and playground stdins and
+// outputs, until API is closed.
+func (api *MyCodeAPI) runBlobSweeper() {
+	defer api.wg.Done()
+
+	log := api.log.WithField("worker", "blob_sweeper")
+
+	t := time.NewTicker(blobSweepInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-api.stop:
+			return
+		case <-t.C:
+		}
+
+		deleted, err := api.sweepBlobs(context.Background())
+		if err != nil {
+			log.WithError(err).Error("failed to sweep blobs")
+		} else if deleted > 0 {
+			log.WithField("count", deleted).Info("unreferenced blobs deleted")
+		}
+	}
+}
+
+// sweepBlobs deletes unreferenced blobs put before the grace period.
+func (api *MyCodeAPI) sweepBlobs(ctx context.Context) (int, error) {
+
+	var (
+		keys    []string
+		deleted int
+	)
+
+	sweep := func() error {
+		ks, err := api.unreferencedBlobs(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for _, k := range ks {
+			err = api.blobs.Delete(ctx, k)
+			if err != nil {
+				return fmt.Errorf("delete blob %s: %w", k, err)
+			}
+			deleted++
+		}
+		keys = keys[:0]
+		return nil
+	}
+
+	err := api.blobs.Walk(ctx, func(key string, putAt time.Time) error {
+		select {
+		case <-api.stop:
+			return errSweepStopped
+		default:
+		}
+		if time.Since(putAt) < blobSweepGrace {
+			return nil
+		}
+		keys = append(keys, key)
+		if len(keys) < blobSweepBatch {
+			return nil
+		}
+		return sweep()
+	})
+	if err == nil && len(keys) != 0 {
+		err = sweep()
+	}
+	if err != nil {
+		if err == errSweepStopped {
+			return deleted, nil
+		}
+		return deleted, fmt.Errorf("walk blobs: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// unreferencedBlobs returns keys which aren't referenced by tests and
+// solution tests.
+func (api *MyCodeAPI) unreferencedBlobs(ctx context.Context,
+	keys []string) ([]string, error) {
+
+	rows, err := api.db.QueryContext(ctx, `
+		select k from unnest($1::text[]) as k
+		where not exists (
+				select 1 from test
+				where stdin_blob = k or expected_stdout_blob = k
+			)
+			and not exists (
+				select 1 from solution_test
+				where stdout_blob = k or stderr_blob = k
+			)
+	`, pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("get unreferenced blobs from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var ks []string
+
+	for rows.Next() {
+		var k string
+		err = rows.Scan(&k)
+		if err != nil {
+			return nil, fmt.Errorf("get blob key row from DB: %w", err)
+		}
+		ks = append(ks, k)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("blob keys rows error: %w", rows.Err())
+	}
+
+	return ks, nil
+}
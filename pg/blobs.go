@@ -0,0 +1,63 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// putContent stores content larger than blob threshold in the blob
+// storage. It returns content to store inline and its blob key, which is
+// valid only for the stored content. Inline content is empty then.
+func (api *MyCodeAPI) putContent(ctx context.Context, content string) (
+	string, sql.NullString, error) {
+
+	if len(content) <= api.blobThreshold {
+		return content, sql.NullString{}, nil
+	}
+
+	key, err := api.blobs.Put(ctx, []byte(content))
+	if err != nil {
+		return "", sql.NullString{}, fmt.Errorf("put blob: %w", err)
+	}
+
+	return "", sql.NullString{String: key, Valid: true}, nil
+}
+
+// getContent returns inline content or content from the blob storage if
+// blob key is valid.
+func (api *MyCodeAPI) getContent(ctx context.Context, inline string,
+	key sql.NullString) (string, error) {
+
+	if !key.Valid {
+		return inline, nil
+	}
+
+	content, err := api.blobs.Get(ctx, key.String)
+	if err != nil {
+		return "", fmt.Errorf("get blob %s: %w", key.String, err)
+	}
+
+	return string(content), nil
+}
+
+// putRunOutput returns run output to store inline and its blob key. Output
+// already stored in the blob storage by runner is only referenced.
+func (api *MyCodeAPI) putRunOutput(ctx context.Context, output,
+	blobKey string) (string, sql.NullString, error) {
+
+	if blobKey != "" {
+		return "", sql.NullString{String: blobKey, Valid: true}, nil
+	}
+
+	return api.putContent(ctx, output)
+}
+
+// getRunOutput returns run output fetching it from the blob storage if
+// runner stored it there.
+func (api *MyCodeAPI) getRunOutput(ctx context.Context, output,
+	blobKey string) (string, error) {
+
+	return api.getContent(ctx, output,
+		sql.NullString{String: blobKey, Valid: blobKey != ""})
+}
@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+// compareStdout checks program stdout against test expected stdout using
+// test comparison mode.
+func compareStdout(t *mycode.Test, stdout string) bool {
+	switch t.ComparisonMode {
+	case mycode.ComparisonMode_ignore_trailing_whitespace:
+		return equalStrings(trimmedLines(stdout), trimmedLines(t.ExpectedStdout),
+			func(a, b string) bool { return a == b })
+	case mycode.ComparisonMode_tokens:
+		return equalStrings(strings.Fields(stdout),
+			strings.Fields(t.ExpectedStdout),
+			func(a, b string) bool { return a == b })
+	case mycode.ComparisonMode_case_insensitive_tokens:
+		return equalStrings(strings.Fields(stdout),
+			strings.Fields(t.ExpectedStdout), strings.EqualFold)
+	case mycode.ComparisonMode_float_tokens:
+		return equalStrings(strings.Fields(stdout),
+			strings.Fields(t.ExpectedStdout), func(a, b string) bool {
+				return equalFloatTokens(a, b, t.FloatAbsEpsilon,
+					t.FloatRelEpsilon)
+			})
+	case mycode.ComparisonMode_unordered_lines:
+		ls, els := trimmedLines(stdout), trimmedLines(t.ExpectedStdout)
+		sort.Strings(ls)
+		sort.Strings(els)
+		return equalStrings(ls, els, func(a, b string) bool { return a == b })
+	default:
+		return stdout == t.ExpectedStdout
+	}
+}
+
+func equalStrings(a, b []string, equal func(a, b string) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// trimmedLines splits s to lines without trailing whitespaces, CRLF line
+// endings and trailing empty lines.
+func trimmedLines(s string) []string {
+	ls := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, l := range ls {
+		ls[i] = strings.TrimRight(l, " \t\r")
+	}
+	for len(ls) > 0 && ls[len(ls)-1] == "" {
+		ls = ls[:len(ls)-1]
+	}
+	return ls
+}
+
+// equalFloatTokens compares tokens as floats if both of them are numbers.
+// Numbers are equal if they differ not more than absolute epsilon or not
+// more than relative epsilon of expected number.
+func equalFloatTokens(token, expectedToken string, absEpsilon,
+	relEpsilon float64) bool {
+
+	if token == expectedToken {
+		return true
+	}
+
+	v, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return false
+	}
+
+	ev, err := strconv.ParseFloat(expectedToken, 64)
+	if err != nil {
+		return false
+	}
+
+	if math.IsNaN(v) || math.IsNaN(ev) {
+		return false
+	}
+
+	d := math.Abs(v - ev)
+
+	return d <= absEpsilon || d <= relEpsilon*math.Abs(ev)
+}
@@ -0,0 +1,244 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestCompareStdout(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mode           mycode.ComparisonMode
+		absEpsilon     float64
+		relEpsilon     float64
+		stdout         string
+		expectedStdout string
+		want           bool
+	}{{
+		name:           "exact equal",
+		mode:           mycode.ComparisonMode_exact,
+		stdout:         "1 2\n",
+		expectedStdout: "1 2\n",
+		want:           true,
+	}, {
+		name:           "exact trailing newline",
+		mode:           mycode.ComparisonMode_exact,
+		stdout:         "1 2",
+		expectedStdout: "1 2\n",
+		want:           false,
+	}, {
+		name:           "exact CRLF",
+		mode:           mycode.ComparisonMode_exact,
+		stdout:         "1 2\r\n",
+		expectedStdout: "1 2\n",
+		want:           false,
+	}, {
+		name:           "trailing whitespace CRLF",
+		mode:           mycode.ComparisonMode_ignore_trailing_whitespace,
+		stdout:         "1 2\r\n3\r\n",
+		expectedStdout: "1 2\n3\n",
+		want:           true,
+	}, {
+		name:           "trailing whitespace and newlines",
+		mode:           mycode.ComparisonMode_ignore_trailing_whitespace,
+		stdout:         "1 2 \t\n3\n\n\n",
+		expectedStdout: "1 2\n3",
+		want:           true,
+	}, {
+		name:           "trailing whitespace leading space",
+		mode:           mycode.ComparisonMode_ignore_trailing_whitespace,
+		stdout:         " 1 2\n",
+		expectedStdout: "1 2\n",
+		want:           false,
+	}, {
+		name:           "trailing whitespace inner empty line",
+		mode:           mycode.ComparisonMode_ignore_trailing_whitespace,
+		stdout:         "1\n\n2\n",
+		expectedStdout: "1\n2\n",
+		want:           false,
+	}, {
+		name:           "tokens",
+		mode:           mycode.ComparisonMode_tokens,
+		stdout:         "1\r\n  2\t3",
+		expectedStdout: "1 2 3\n",
+		want:           true,
+	}, {
+		name:           "tokens case sensitive",
+		mode:           mycode.ComparisonMode_tokens,
+		stdout:         "YES\n",
+		expectedStdout: "yes\n",
+		want:           false,
+	}, {
+		name:           "tokens missing",
+		mode:           mycode.ComparisonMode_tokens,
+		stdout:         "1 2\n",
+		expectedStdout: "1 2 3\n",
+		want:           false,
+	}, {
+		name:           "case insensitive tokens",
+		mode:           mycode.ComparisonMode_case_insensitive_tokens,
+		stdout:         "Yes\r\nNO\n",
+		expectedStdout: "yes no",
+		want:           true,
+	}, {
+		name:           "case insensitive tokens different",
+		mode:           mycode.ComparisonMode_case_insensitive_tokens,
+		stdout:         "yes",
+		expectedStdout: "no",
+		want:           false,
+	}, {
+		name:           "float tokens within abs epsilon",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1e-3,
+		stdout:         "1.0005 x\n",
+		expectedStdout: "1 x\n",
+		want:           true,
+	}, {
+		name:           "float tokens at abs epsilon",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     0.5,
+		stdout:         "1.5",
+		expectedStdout: "1",
+		want:           true,
+	}, {
+		name:           "float tokens beyond abs epsilon",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1e-3,
+		stdout:         "1.002",
+		expectedStdout: "1",
+		want:           false,
+	}, {
+		name:           "float tokens within rel epsilon",
+		mode:           mycode.ComparisonMode_float_tokens,
+		relEpsilon:     1e-3,
+		stdout:         "1000.5",
+		expectedStdout: "1000",
+		want:           true,
+	}, {
+		name:           "float tokens rel epsilon of expected",
+		mode:           mycode.ComparisonMode_float_tokens,
+		relEpsilon:     0.5,
+		stdout:         "0.5",
+		expectedStdout: "0.25",
+		want:           false,
+	}, {
+		name:           "float tokens beyond both epsilons",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1e-6,
+		relEpsilon:     1e-6,
+		stdout:         "1000.01",
+		expectedStdout: "1000",
+		want:           false,
+	}, {
+		name:           "float tokens exponent",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1e-9,
+		stdout:         "1e3",
+		expectedStdout: "1000.0",
+		want:           true,
+	}, {
+		name:           "float tokens NaN",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1,
+		stdout:         "NaN",
+		expectedStdout: "1",
+		want:           false,
+	}, {
+		name:           "float tokens same NaN",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1,
+		stdout:         "NaN",
+		expectedStdout: "NaN",
+		want:           true,
+	}, {
+		name:           "float tokens different NaN",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1,
+		stdout:         "nan",
+		expectedStdout: "NaN",
+		want:           false,
+	}, {
+		name:           "float tokens non-numeric",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1,
+		stdout:         "1,5",
+		expectedStdout: "1.5",
+		want:           false,
+	}, {
+		name:           "float tokens words compared exactly",
+		mode:           mycode.ComparisonMode_float_tokens,
+		absEpsilon:     1,
+		stdout:         "Yes",
+		expectedStdout: "yes",
+		want:           false,
+	}, {
+		name:           "unordered lines",
+		mode:           mycode.ComparisonMode_unordered_lines,
+		stdout:         "b\r\na \nc\n",
+		expectedStdout: "a\nc\nb\n\n",
+		want:           true,
+	}, {
+		name:           "unordered lines duplicates",
+		mode:           mycode.ComparisonMode_unordered_lines,
+		stdout:         "a\nb\na\n",
+		expectedStdout: "a\na\nb\n",
+		want:           true,
+	}, {
+		name:           "unordered lines duplicates count",
+		mode:           mycode.ComparisonMode_unordered_lines,
+		stdout:         "a\nb\nb\n",
+		expectedStdout: "a\na\nb\n",
+		want:           false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareStdout(&mycode.Test{
+				ComparisonMode:  tt.mode,
+				FloatAbsEpsilon: tt.absEpsilon,
+				FloatRelEpsilon: tt.relEpsilon,
+				ExpectedStdout:  tt.expectedStdout,
+			}, tt.stdout)
+			if got != tt.want {
+				t.Errorf("equal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimmedLines(t *testing.T) {
+
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{{
+		name: "empty",
+		s:    "",
+		want: []string{},
+	}, {
+		name: "only newlines",
+		s:    "\n\r\n \n",
+		want: []string{},
+	}, {
+		name: "CRLF and trailing whitespace",
+		s:    "a \r\n\tb\t\r\n\r\n",
+		want: []string{"a", "\tb"},
+	}, {
+		name: "lone CR",
+		s:    "a\rb\n",
+		want: []string{"a\rb"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimmedLines(tt.s)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	maxGrade = 100
+
+	// estimatorSteepness defines how much exponential and logarithmic
+	// estimators bend away from the linear one.
+	estimatorSteepness = 3
+)
+
+// estimate converts passed tests share to the grade from 0 to maxGrade.
+// Partially passed tests are counted with their scores.
+// Exponential estimator rewards nearly complete solutions, logarithmic
+// one rewards any progress.
+func estimate(e mycode.ExerciseEstimator, passed, total float64) (
+	float64, error) {
+
+	if total <= 0 {
+		return 0, fmt.Errorf("no tests to estimate")
+	}
+
+	p := passed / total
+
+	var g float64
+
+	switch e {
+	case mycode.ExerciseEstimator_linear:
+		g = p
+	case mycode.ExerciseEstimator_exponential:
+		g = math.Expm1(estimatorSteepness*p) / math.Expm1(estimatorSteepness)
+	case mycode.ExerciseEstimator_logarithmic:
+		g = math.Log1p(p*math.Expm1(estimatorSteepness)) / estimatorSteepness
+	default:
+		return 0, fmt.Errorf("unexpected estimator: %v", e)
+	}
+
+	return math.Round(g*maxGrade*100) / 100, nil
+}
+
+// penalize reduces late solution grade by the penalty share.
+func penalize(grade, penalty float64) float64 {
+	return math.Round(grade*(1-penalty)*100) / 100
+}
+
+// gradeSolution sets solution grade when all its tests are processed.
+// Solution row is locked, so concurrently finished tests can't both miss
+// the moment when the last one is done. Late solution grade is penalized
+// with the assignment late penalty.
+func gradeSolution(ctx context.Context, tx *sql.Tx, solutionID int64) error {
+
+	var (
+		estimator   mycode.ExerciseEstimator
+		late        bool
+		latePenalty float64
+	)
+
+	err := tx.QueryRowContext(ctx, `
+		select e.estimator, s.late, se.late_penalty from solution as s
+		join exercise as e on s.exercise_id = e.id
+		join student_exercise as se on s.student_id = se.student_id
+			and s.exercise_id = se.exercise_id
+		where s.id = $1
+		for update of s
+	`, solutionID).Scan(&estimator, &late, &latePenalty)
+	if err != nil {
+		return fmt.Errorf("get solution estimator from DB: %w", err)
+	}
+
+	var (
+		total, processing int
+		passed            float64
+	)
+
+	err = tx.QueryRowContext(ctx, `
+		select count(*),
+			count(*) filter (where status = $2),
+			coalesce(sum(score), 0)
+		from solution_test
+		where solution_id = $1
+	`, solutionID, mycode.SolutionTestStatus_processing).
+		Scan(&total, &processing, &passed)
+	if err != nil {
+		return fmt.Errorf("get solution tests stats from DB: %w", err)
+	}
+
+	if processing != 0 {
+		return nil
+	}
+
+	grade, err := estimate(estimator, passed, float64(total))
+	if err != nil {
+		return fmt.Errorf("estimate solution: %w", err)
+	}
+
+	if late {
+		grade = penalize(grade, latePenalty)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		update solution set grade = $1 where id = $2
+	`, grade, solutionID)
+	if err != nil {
+		return fmt.Errorf("update solution grade in DB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestEstimate(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		estimator     mycode.ExerciseEstimator
+		passed, total float64
+		want          float64
+		wantErr       bool
+	}{{
+		name:      "linear none passed",
+		estimator: mycode.ExerciseEstimator_linear,
+		passed:    0,
+		total:     4,
+		want:      0,
+	}, {
+		name:      "linear all passed",
+		estimator: mycode.ExerciseEstimator_linear,
+		passed:    4,
+		total:     4,
+		want:      100,
+	}, {
+		name:      "linear rounded",
+		estimator: mycode.ExerciseEstimator_linear,
+		passed:    1,
+		total:     3,
+		want:      33.33,
+	}, {
+		name:      "linear partially passed",
+		estimator: mycode.ExerciseEstimator_linear,
+		passed:    1.5,
+		total:     2,
+		want:      75,
+	}, {
+		name:      "exponential none passed",
+		estimator: mycode.ExerciseEstimator_exponential,
+		passed:    0,
+		total:     4,
+		want:      0,
+	}, {
+		name:      "exponential half passed",
+		estimator: mycode.ExerciseEstimator_exponential,
+		passed:    2,
+		total:     4,
+		want:      18.24,
+	}, {
+		name:      "exponential all passed",
+		estimator: mycode.ExerciseEstimator_exponential,
+		passed:    4,
+		total:     4,
+		want:      100,
+	}, {
+		name:      "logarithmic none passed",
+		estimator: mycode.ExerciseEstimator_logarithmic,
+		passed:    0,
+		total:     4,
+		want:      0,
+	}, {
+		name:      "logarithmic quarter passed",
+		estimator: mycode.ExerciseEstimator_logarithmic,
+		passed:    1,
+		total:     4,
+		want:      58.43,
+	}, {
+		name:      "logarithmic half passed",
+		estimator: mycode.ExerciseEstimator_logarithmic,
+		passed:    2,
+		total:     4,
+		want:      78.51,
+	}, {
+		name:      "logarithmic all passed",
+		estimator: mycode.ExerciseEstimator_logarithmic,
+		passed:    4,
+		total:     4,
+		want:      100,
+	}, {
+		name:      "no tests",
+		estimator: mycode.ExerciseEstimator_linear,
+		passed:    0,
+		total:     0,
+		wantErr:   true,
+	}, {
+		name:      "unexpected estimator",
+		estimator: mycode.ExerciseEstimator(100),
+		passed:    1,
+		total:     1,
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := estimate(tt.estimator, tt.passed, tt.total)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("grade = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPenalize(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		grade, penalty float64
+		want           float64
+	}{{
+		name:    "no penalty",
+		grade:   78.51,
+		penalty: 0,
+		want:    78.51,
+	}, {
+		name:    "full penalty",
+		grade:   78.51,
+		penalty: 1,
+		want:    0,
+	}, {
+		name:    "rounded",
+		grade:   78.51,
+		penalty: 0.3,
+		want:    54.96,
+	}, {
+		name:    "zero grade",
+		grade:   0,
+		penalty: 0.5,
+		want:    0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := penalize(tt.grade, tt.penalty)
+			if got != tt.want {
+				t.Errorf("grade = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package pg
+
+import (
+	"sync"
+
+	"github.com/dimuls/mycode"
+)
+
+const eventsBufferSize = 64
+
+type eventsSubscriber struct {
+	role string
+	id   int64
+}
+
+// events delivers solution test updates to the subscribed students and
+// teachers. Slow subscribers lose updates instead of blocking run handling.
+type events struct {
+	mu          sync.Mutex
+	subscribers map[eventsSubscriber]map[chan *mycode.SolutionTest]struct{}
+	closed      bool
+}
+
+func newEvents() *events {
+	return &events{
+		subscribers: map[eventsSubscriber]map[chan *mycode.SolutionTest]struct{}{},
+	}
+}
+
+func (e *events) subscribe(s eventsSubscriber) chan *mycode.SolutionTest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ch := make(chan *mycode.SolutionTest, eventsBufferSize)
+
+	if e.closed {
+		close(ch)
+		return ch
+	}
+
+	chs, exists := e.subscribers[s]
+	if !exists {
+		chs = map[chan *mycode.SolutionTest]struct{}{}
+		e.subscribers[s] = chs
+	}
+
+	chs[ch] = struct{}{}
+
+	return ch
+}
+
+func (e *events) unsubscribe(s eventsSubscriber, ch chan *mycode.SolutionTest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	chs, exists := e.subscribers[s]
+	if !exists {
+		return
+	}
+
+	if _, exists := chs[ch]; !exists {
+		return
+	}
+
+	delete(chs, ch)
+	close(ch)
+
+	if len(chs) == 0 {
+		delete(e.subscribers, s)
+	}
+}
+
+// subscribed returns true if any of the subscribers is subscribed.
+func (e *events) subscribed(ss ...eventsSubscriber) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range ss {
+		if len(e.subscribers[s]) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *events) publish(st *mycode.SolutionTest, ss ...eventsSubscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range ss {
+		for ch := range e.subscribers[s] {
+			select {
+			case ch <- st:
+			default:
+			}
+		}
+	}
+}
+
+func (e *events) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for s, chs := range e.subscribers {
+		for ch := range chs {
+			close(ch)
+		}
+		delete(e.subscribers, s)
+	}
+
+	e.closed = true
+}
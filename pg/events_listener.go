@@ -0,0 +1,133 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const solutionTestEventsChannel = "solution_test_events"
+
+// solutionTestEvent is the solution test update notification. Solution
+// test itself is too large for the notification payload and is fetched by
+// the API instances having its subscribers.
+type solutionTestEvent struct {
+	SolutionTestID int64 `json:"solution_test_id"`
+	StudentID      int64 `json:"student_id"`
+	TeacherID      int64 `json:"teacher_id"`
+}
+
+// notifySolutionTest notifies all API instances about the solution test
+// update. Notification is delivered after the transaction commit.
+func notifySolutionTest(ctx context.Context, tx *sql.Tx,
+	e solutionTestEvent) error {
+
+	eventJSON, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("JSON marshal event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		select pg_notify($1, $2)
+	`, solutionTestEventsChannel, string(eventJSON))
+	if err != nil {
+		return fmt.Errorf("notify in DB: %w", err)
+	}
+
+	return nil
+}
+
+// runEventsListener publishes solution test updates notified by any API
+// instance to the subscribers of this instance and wakes up RunCode calls
+// waiting for the stored playground runs, until API is closed. Updates
+// notified while listener is reconnecting are lost.
+func (api *MyCodeAPI) runEventsListener() {
+	defer api.wg.Done()
+
+	log := api.log.WithField("worker", "events_listener")
+
+	l := pq.NewListener(api.pgURI, time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.WithError(err).Warn("listener connection failed")
+			}
+		})
+
+	go func() {
+		<-api.stop
+		l.Close()
+	}()
+
+	for _, ch := range []string{solutionTestEventsChannel,
+		playgroundRunsChannel} {
+
+		err := l.Listen(ch)
+		if err != nil {
+			select {
+			case <-api.stop:
+			default:
+				log.WithError(err).WithField("channel", ch).
+					Error("failed to listen")
+			}
+			return
+		}
+	}
+
+	for n := range l.Notify {
+		// Nil notification is sent after reconnect.
+		if n == nil {
+			continue
+		}
+
+		if n.Channel == playgroundRunsChannel {
+			api.playground.wakeUp(n.Extra)
+			continue
+		}
+
+		var e solutionTestEvent
+
+		err := json.Unmarshal([]byte(n.Extra), &e)
+		if err != nil {
+			log.WithError(err).Error("failed to JSON unmarshal event")
+			continue
+		}
+
+		err = api.publishSolutionTest(context.Background(), e)
+		if err != nil {
+			log.WithError(err).WithField("solution_test_id",
+				e.SolutionTestID).Error("failed to publish solution test")
+		}
+	}
+}
+
+// publishSolutionTest publishes solution test to the student who owns the
+// solution and to the student's teacher if they are subscribed.
+func (api *MyCodeAPI) publishSolutionTest(ctx context.Context,
+	e solutionTestEvent) error {
+
+	ss := []eventsSubscriber{
+		{role: ctxStudent, id: e.StudentID},
+		{role: ctxTeacher, id: e.TeacherID},
+	}
+
+	if !api.events.subscribed(ss...) {
+		return nil
+	}
+
+	st, err := api.scanSolutionTest(ctx, api.db.QueryRowContext(ctx, `
+		select `+solutionTestColumns+`
+		from solution_test as st
+		where st.id = $1
+	`, e.SolutionTestID))
+	if err != nil {
+		return err
+	}
+
+	api.events.publish(st, ss...)
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// limiter is token bucket limiting codes published to the shared runners
+// pool. Bucket is stored in DB, so it is shared by all API instances.
+// Bucket is refilled with rate tokens per second up to burst tokens. Zero
+// rate disables limiting.
+type limiter struct {
+	db    *sql.DB
+	rate  float64
+	burst int
+}
+
+func newLimiter(db *sql.DB, rate float64, burst int) *limiter {
+	return &limiter{
+		db:    db,
+		rate:  rate,
+		burst: burst,
+	}
+}
+
+// reserve locks bucket in the transaction and returns how many of n
+// tokens it has. Reserved tokens are taken with take in the same
+// transaction, so tokens of the codes not published stay in the bucket.
+func (l *limiter) reserve(ctx context.Context, tx *sql.Tx, n int) (int,
+	error) {
+
+	if l.rate <= 0 {
+		return n, nil
+	}
+
+	var reserved int
+
+	err := tx.QueryRowContext(ctx, `
+		select least(floor(least($1, tokens + $2 *
+			extract(epoch from clock_timestamp() - updated_at))), $3)::int
+		from code_bucket
+		for update
+	`, l.burst, l.rate, n).Scan(&reserved)
+	if err != nil {
+		return 0, fmt.Errorf("reserve tokens in DB: %w", err)
+	}
+
+	return reserved, nil
+}
+
+// take takes n tokens reserved in the transaction.
+func (l *limiter) take(ctx context.Context, tx *sql.Tx, n int) error {
+	if l.rate <= 0 || n == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		update code_bucket
+		set tokens = least($1, tokens + $2 *
+				extract(epoch from clock_timestamp() - updated_at)) - $3,
+			updated_at = clock_timestamp()
+	`, l.burst, l.rate, n)
+	if err != nil {
+		return fmt.Errorf("take tokens in DB: %w", err)
+	}
+
+	return nil
+}
+
+// busy reports whether outbox has more codes than bucket burst, so the new
+// codes would wait too long to be published.
+func (l *limiter) busy(ctx context.Context) (bool, error) {
+	if l.rate <= 0 {
+		return false, nil
+	}
+
+	var busy bool
+
+	err := l.db.QueryRowContext(ctx, `
+		select exists (select 1 from code_outbox offset $1)
+	`, l.burst).Scan(&busy)
+	if err != nil {
+		return false, fmt.Errorf("check outbox size in DB: %w", err)
+	}
+
+	return busy, nil
+}
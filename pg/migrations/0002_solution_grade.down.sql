@@ -0,0 +1 @@
+alter table solution drop column grade;
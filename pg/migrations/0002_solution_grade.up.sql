@@ -0,0 +1 @@
+alter table solution add column grade double precision;
@@ -0,0 +1 @@
+alter table solution_test drop column compiler_output;
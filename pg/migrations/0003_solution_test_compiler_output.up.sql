@@ -0,0 +1 @@
+alter table solution_test add column compiler_output text;
@@ -0,0 +1,4 @@
+alter table solution_test
+    drop column verdict,
+    drop column exit_code,
+    drop column signal;
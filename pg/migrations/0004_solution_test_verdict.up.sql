@@ -0,0 +1,4 @@
+alter table solution_test
+    add column verdict int,
+    add column exit_code int,
+    add column signal int;
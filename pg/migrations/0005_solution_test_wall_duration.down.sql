@@ -0,0 +1,2 @@
+alter table solution_test
+    drop column wall_duration;
@@ -0,0 +1,2 @@
+alter table solution_test
+    add column wall_duration text;
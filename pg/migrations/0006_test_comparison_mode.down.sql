@@ -0,0 +1,4 @@
+alter table test
+    drop column comparison_mode,
+    drop column float_abs_epsilon,
+    drop column float_rel_epsilon;
@@ -0,0 +1,4 @@
+alter table test
+    add column comparison_mode int not null default 0,
+    add column float_abs_epsilon double precision not null default 0,
+    add column float_rel_epsilon double precision not null default 0;
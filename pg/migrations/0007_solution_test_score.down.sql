@@ -0,0 +1,3 @@
+alter table solution_test
+    drop column score,
+    drop column checker_message;
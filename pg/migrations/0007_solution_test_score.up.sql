@@ -0,0 +1,6 @@
+alter table solution_test
+    add column score double precision,
+    add column checker_message text;
+
+update solution_test set score = case when status = '2' then 1 else 0 end
+where status <> '0';
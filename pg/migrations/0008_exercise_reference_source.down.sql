@@ -0,0 +1,7 @@
+alter table test
+    drop column expected_stdout_pending,
+    drop column expected_stdout_attempt,
+    drop column expected_stdout_error;
+
+alter table exercise
+    drop column reference_source;
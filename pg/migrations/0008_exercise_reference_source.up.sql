@@ -0,0 +1,7 @@
+alter table exercise
+    add column reference_source text;
+
+alter table test
+    add column expected_stdout_pending bool not null default false,
+    add column expected_stdout_attempt bigint not null default 0,
+    add column expected_stdout_error text;
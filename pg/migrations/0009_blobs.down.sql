@@ -0,0 +1,7 @@
+alter table solution_test
+    drop column stdout_blob,
+    drop column stderr_blob;
+
+alter table test
+    drop column stdin_blob,
+    drop column expected_stdout_blob;
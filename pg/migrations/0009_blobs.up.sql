@@ -0,0 +1,7 @@
+alter table test
+    add column stdin_blob text,
+    add column expected_stdout_blob text;
+
+alter table solution_test
+    add column stdout_blob text,
+    add column stderr_blob text;
@@ -0,0 +1,2 @@
+alter table solution_test
+    add column attempt bigint not null default 0;
@@ -0,0 +1,8 @@
+alter table solution
+    drop column late;
+
+alter table student_exercise
+    drop column opens_at,
+    drop column closes_at,
+    drop column late_policy,
+    drop column late_penalty;
@@ -0,0 +1,8 @@
+alter table student_exercise
+    add column opens_at timestamptz,
+    add column closes_at timestamptz,
+    add column late_policy int not null default 0,
+    add column late_penalty double precision not null default 0;
+
+alter table solution
+    add column late bool not null default false;
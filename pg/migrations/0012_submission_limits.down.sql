@@ -0,0 +1,6 @@
+alter table solution
+    drop column created_at;
+
+alter table exercise
+    drop column max_attempts,
+    drop column submission_cooldown;
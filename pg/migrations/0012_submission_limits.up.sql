@@ -0,0 +1,6 @@
+alter table exercise
+    add column max_attempts int not null default 0,
+    add column submission_cooldown text;
+
+alter table solution
+    add column created_at timestamptz not null default now();
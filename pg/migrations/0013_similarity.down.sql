@@ -0,0 +1,4 @@
+drop table similarity_pair;
+
+alter table exercise
+    drop column similarity_pending;
@@ -0,0 +1,17 @@
+alter table exercise
+    add column similarity_pending bool not null default false;
+
+update exercise as e set similarity_pending = true
+where exists (select 1 from solution where exercise_id = e.id);
+
+create table similarity_pair (
+    exercise_id bigint not null references exercise (id) on delete cascade,
+    solution_a_id bigint not null references solution (id) on delete cascade,
+    solution_b_id bigint not null references solution (id) on delete cascade,
+    score double precision not null,
+    fragments jsonb not null,
+
+    primary key (solution_a_id, solution_b_id)
+);
+
+create index on similarity_pair (exercise_id);
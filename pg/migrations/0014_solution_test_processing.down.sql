@@ -0,0 +1,3 @@
+alter table solution_test
+    drop column processing_since,
+    drop column republishes;
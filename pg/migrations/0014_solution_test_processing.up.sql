@@ -0,0 +1,5 @@
+alter table solution_test
+    add column processing_since timestamptz not null default now(),
+    add column republishes int not null default 0;
+
+create index on solution_test (processing_since) where status = '0';
@@ -0,0 +1,4 @@
+create table code_outbox (
+    id bigserial primary key,
+    code jsonb not null
+);
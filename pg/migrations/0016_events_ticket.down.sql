@@ -0,0 +1 @@
+drop table events_ticket;
@@ -0,0 +1,6 @@
+create table events_ticket (
+    ticket text primary key,
+    user_id bigint not null references "user" (id) on delete cascade,
+    user_role text not null,
+    expires_at timestamptz not null
+);
@@ -0,0 +1,2 @@
+alter table test
+    drop column checker_protocol;
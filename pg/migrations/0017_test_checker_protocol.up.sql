@@ -0,0 +1,2 @@
+alter table test
+    add column checker_protocol int not null default 0;
@@ -0,0 +1,2 @@
+alter table test
+    drop column expected_stdout_pending_since;
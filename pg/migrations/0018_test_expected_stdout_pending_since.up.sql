@@ -0,0 +1,8 @@
+alter table test
+    add column expected_stdout_pending_since timestamptz;
+
+update test set expected_stdout_pending_since = now()
+where expected_stdout_pending;
+
+create index on test (expected_stdout_pending_since)
+where expected_stdout_pending;
@@ -0,0 +1 @@
+drop table playground_run;
@@ -0,0 +1,5 @@
+create table playground_run (
+    id text primary key,
+    run jsonb not null,
+    created_at timestamptz not null default now()
+);
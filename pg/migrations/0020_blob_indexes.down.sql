@@ -0,0 +1,5 @@
+drop index test_stdin_blob_idx;
+drop index test_expected_stdout_blob_idx;
+
+drop index solution_test_stdout_blob_idx;
+drop index solution_test_stderr_blob_idx;
@@ -0,0 +1,6 @@
+create index on test (stdin_blob) where stdin_blob is not null;
+create index on test (expected_stdout_blob)
+where expected_stdout_blob is not null;
+
+create index on solution_test (stdout_blob) where stdout_blob is not null;
+create index on solution_test (stderr_blob) where stderr_blob is not null;
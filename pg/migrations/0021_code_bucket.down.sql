@@ -0,0 +1 @@
+drop table code_bucket;
@@ -0,0 +1,6 @@
+create table code_bucket (
+    tokens double precision not null,
+    updated_at timestamptz not null
+);
+
+insert into code_bucket (tokens, updated_at) values (0, now());
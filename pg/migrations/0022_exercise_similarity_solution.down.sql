@@ -0,0 +1,11 @@
+alter table exercise
+    add column similarity_pending bool not null default false;
+
+update exercise as e set similarity_pending = true
+where exists (
+    select 1 from solution
+    where exercise_id = e.id and id > e.similarity_solution_id
+);
+
+alter table exercise
+    drop column similarity_solution_id;
@@ -0,0 +1,10 @@
+alter table exercise
+    add column similarity_solution_id bigint not null default 0;
+
+update exercise as e set similarity_solution_id = coalesce((
+    select max(id) from solution where exercise_id = e.id
+), 0)
+where not e.similarity_pending;
+
+alter table exercise
+    drop column similarity_pending;
@@ -0,0 +1 @@
+alter table code_outbox drop column class_id;
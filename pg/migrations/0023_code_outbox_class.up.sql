@@ -0,0 +1,3 @@
+alter table code_outbox add column class_id bigint;
+
+create index on code_outbox (class_id, id);
@@ -0,0 +1,3 @@
+alter table code_outbox
+    drop column solution_test_id,
+    drop column reference_test_id;
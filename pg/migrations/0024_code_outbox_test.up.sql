@@ -0,0 +1,12 @@
+alter table code_outbox
+    add column solution_test_id bigint,
+    add column reference_test_id bigint;
+
+update code_outbox set
+    solution_test_id = coalesce(code->>'solutionTestId',
+        code->>'solution_test_id')::bigint,
+    reference_test_id = coalesce(code->>'referenceTestId',
+        code->>'reference_test_id')::bigint;
+
+create index on code_outbox (solution_test_id);
+create index on code_outbox (reference_test_id);
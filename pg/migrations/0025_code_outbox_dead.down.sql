@@ -0,0 +1 @@
+drop table code_outbox_dead;
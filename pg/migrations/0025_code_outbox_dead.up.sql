@@ -0,0 +1,6 @@
+create table code_outbox_dead (
+    id bigint primary key,
+    code jsonb not null,
+    error text not null,
+    created_at timestamptz not null default now()
+);
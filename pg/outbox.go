@@ -0,0 +1,261 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	// outboxInterval is the interval of outbox polling, relay is also woken
+	// up after codes are enqueued by this API instance.
+	outboxInterval = time.Second
+
+	outboxBatch = 100
+)
+
+// enqueueCodes adds codes to the outbox in the transaction. Outbox relay
+// publishes them after commit, so codes of the rolled back changes are
+// never published and codes of the committed ones are published at least
+// once. Call notifyOutbox after commit to publish codes without delay.
+// Solution test codes are queued by student class, the other codes share
+// one queue.
+func enqueueCodes(ctx context.Context, tx *sql.Tx, cs []*mycode.Code) error {
+
+	for _, c := range cs {
+		codeJSON, err := (&jsonpb.Marshaler{}).MarshalToString(c)
+		if err != nil {
+			return fmt.Errorf("JSON marshal code: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			insert into code_outbox (code, class_id, solution_test_id,
+				reference_test_id)
+			values ($1, (
+				select s.class_id
+				from solution_test as st
+				join solution as so on so.id = st.solution_id
+				join student as s on s.id = so.student_id
+				where st.id = $2
+			), nullif($2, 0), nullif($3, 0))
+		`, codeJSON, c.SolutionTestId, c.ReferenceTestId)
+		if err != nil {
+			return fmt.Errorf("add code to outbox in DB: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (api *MyCodeAPI) notifyOutbox() {
+	select {
+	case api.outbox <- struct{}{}:
+	default:
+	}
+}
+
+// runOutboxRelay publishes outbox codes until API is closed.
+func (api *MyCodeAPI) runOutboxRelay() {
+	defer api.wg.Done()
+
+	log := api.log.WithField("worker", "outbox_relay")
+
+	t := time.NewTicker(outboxInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-api.stop:
+			return
+		case <-t.C:
+		case <-api.outbox:
+		}
+
+		for {
+			published, err := api.relayCodes(context.Background())
+			if err != nil {
+				log.WithError(err).Error("failed to relay codes")
+				break
+			}
+
+			if published < outboxBatch {
+				break
+			}
+		}
+	}
+}
+
+// relayCodes publishes batch of outbox codes and deletes published ones.
+// Class queues are relayed round-robin in order, so codes of one class
+// can't hold back the other classes. Codes are locked, so concurrent relays
+// of the other API instances publish the other codes. Undecodable codes are
+// moved to the dead codes, so they don't block the outbox. Codes exceeding
+// the codes limit are left in the outbox. Limiter tokens are taken in the
+// same transaction, so they are spent only on the published codes. Stuck
+// timeouts of the published codes start on publish, not on enqueue.
+func (api *MyCodeAPI) relayCodes(ctx context.Context) (
+	published int, err error) {
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+		select o.id, o.code
+		from code_outbox as o
+		join (
+			select id, row_number() over (
+				partition by class_id order by id) as n
+			from code_outbox
+		) as q on q.id = o.id
+		order by q.n, o.id
+		limit $1 for update of o skip locked
+	`, outboxBatch)
+	if err != nil {
+		return 0, fmt.Errorf("get outbox codes from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		ids      []int64
+		cs       []*mycode.Code
+		deadIDs  []int64
+		deadErrs []error
+	)
+
+	for rows.Next() {
+		var (
+			id       int64
+			codeJSON string
+			c        = &mycode.Code{}
+		)
+		err = rows.Scan(&id, &codeJSON)
+		if err != nil {
+			return 0, fmt.Errorf("get outbox code row from DB: %w", err)
+		}
+		decodeErr := jsonpb.UnmarshalString(codeJSON, c)
+		if decodeErr != nil {
+			deadIDs = append(deadIDs, id)
+			deadErrs = append(deadErrs, decodeErr)
+			continue
+		}
+		ids = append(ids, id)
+		cs = append(cs, c)
+	}
+
+	if rows.Err() != nil {
+		err = rows.Err()
+		return 0, fmt.Errorf("outbox codes rows error: %w", err)
+	}
+
+	for i, id := range deadIDs {
+		_, err = tx.ExecContext(ctx, `
+			with o as (
+				delete from code_outbox where id = $1 returning id, code
+			)
+			insert into code_outbox_dead (id, code, error)
+			select id, code, $2 from o
+		`, id, deadErrs[i].Error())
+		if err != nil {
+			return 0, fmt.Errorf("move outbox code to dead in DB: %w", err)
+		}
+
+		api.log.WithError(deadErrs[i]).WithField("code_id", id).
+			Error("undecodable outbox code moved to dead codes")
+	}
+
+	if len(cs) != 0 {
+		var reserved int
+
+		reserved, err = api.codesLimiter.reserve(ctx, tx, len(cs))
+		if err != nil {
+			return 0, fmt.Errorf("reserve codes limiter tokens: %w", err)
+		}
+
+		cs = cs[:reserved]
+	}
+
+	// Codes published before the failure are deleted, the rest are
+	// published later.
+	var publishErr error
+
+	for i, c := range cs {
+		publishErr = api.codePublisher.PublishCode(c)
+		if publishErr != nil {
+			break
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			delete from code_outbox where id = $1
+		`, ids[i])
+		if err != nil {
+			return 0, fmt.Errorf("delete outbox code from DB: %w", err)
+		}
+
+		err = markPublished(ctx, tx, c)
+		if err != nil {
+			return 0, err
+		}
+
+		published++
+	}
+
+	err = api.codesLimiter.take(ctx, tx, published)
+	if err != nil {
+		return 0, fmt.Errorf("take codes limiter tokens: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+
+	if publishErr != nil {
+		return published, fmt.Errorf("publish code: %w", publishErr)
+	}
+
+	return published, nil
+}
+
+// markPublished restarts stuck timeout of the published code solution test
+// or reference test, since code could wait for the limiter tokens longer.
+func markPublished(ctx context.Context, tx *sql.Tx, c *mycode.Code) error {
+
+	if c.SolutionTestId != 0 {
+		_, err := tx.ExecContext(ctx, `
+			update solution_test set processing_since = now()
+			where id = $1 and status = $2
+		`, c.SolutionTestId, mycode.SolutionTestStatus_processing)
+		if err != nil {
+			return fmt.Errorf("update solution test in DB: %w", err)
+		}
+	}
+
+	if c.ReferenceTestId != 0 {
+		_, err := tx.ExecContext(ctx, `
+			update test set expected_stdout_pending_since = now()
+			where id = $1 and expected_stdout_pending
+		`, c.ReferenceTestId)
+		if err != nil {
+			return fmt.Errorf("update test in DB: %w", err)
+		}
+	}
+
+	return nil
+}
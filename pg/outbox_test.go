@@ -0,0 +1,52 @@
+package pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+func TestRelayCodesMovesUndecodable(t *testing.T) {
+
+	var published []*mycode.Code
+
+	api := testAPI(t, codePublisherFunc(func(c *mycode.Code) error {
+		published = append(published, c)
+		return nil
+	}), 0, 10)
+
+	ctx := context.Background()
+
+	_, err := api.db.Exec(`
+		insert into code_outbox (code) values
+			('"not a code"'), ('{"source": "print(1)"}')
+	`)
+	if err != nil {
+		t.Fatalf("add codes to DB: %v", err)
+	}
+
+	n, err := api.relayCodes(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("relay codes = %d, %v, want 1", n, err)
+	}
+
+	if len(published) != 1 || published[0].Source != "print(1)" {
+		t.Errorf("published codes = %v, want decodable code", published)
+	}
+
+	var outbox, dead int
+
+	err = api.db.QueryRow(`
+		select (select count(*) from code_outbox),
+			(select count(*) from code_outbox_dead)
+	`).Scan(&outbox, &dead)
+	if err != nil {
+		t.Fatalf("count codes in DB: %v", err)
+	}
+
+	if outbox != 0 || dead != 1 {
+		t.Errorf("outbox codes = %d, dead codes = %d, want 0 and 1",
+			outbox, dead)
+	}
+}
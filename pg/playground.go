@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"sync"
+)
+
+// playground wakes up RunCode calls waiting for the runs of not saved codes
+// when runs are stored.
+type playground struct {
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+func newPlayground() *playground {
+	return &playground{
+		waiters: map[string]chan struct{}{},
+	}
+}
+
+func (p *playground) wait(id string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	p.waiters[id] = ch
+
+	return ch
+}
+
+func (p *playground) cancel(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.waiters, id)
+}
+
+func (p *playground) wakeUp(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch, exists := p.waiters[id]
+	if !exists {
+		return
+	}
+
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
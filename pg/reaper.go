@@ -0,0 +1,254 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	reaperInterval = time.Minute
+
+	// stuckTimeout is the time after which processing solution test is
+	// considered stuck, because its code or run was lost. Codes waiting in
+	// the outbox aren't lost, timeout starts after their publishing.
+	stuckTimeout = 15 * time.Minute
+
+	// maxRepublishes is the max count of stuck solution test code
+	// republishes. Solution test still stuck after them gets internal error.
+	maxRepublishes = 2
+
+	// reaperBatch is the max count of stuck solution tests reaped at once.
+	reaperBatch = 100
+)
+
+var errStuck = errors.New("solution test stuck in processing")
+
+// errReferenceStuck is the error of the expected stdout generation stuck
+// because its reference code or run was lost.
+var errReferenceStuck = errors.New(
+	"expected stdout generation timed out, regenerate expected stdouts")
+
+// runReaper periodically republishes codes of the stuck solution tests,
+// fails ones stuck after max republishes, fails stuck expected stdouts
+// generations and deletes playground runs nobody took, until API is closed.
+func (api *MyCodeAPI) runReaper() {
+	defer api.wg.Done()
+
+	log := api.log.WithField("worker", "reaper")
+
+	t := time.NewTicker(reaperInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-api.stop:
+			return
+		case <-t.C:
+		}
+
+		ctx := context.Background()
+
+		republished, err := api.republishStuck(ctx)
+		if err != nil {
+			log.WithError(err).Error("failed to republish stuck codes")
+		} else if republished > 0 {
+			log.WithField("count", republished).
+				Warn("stuck codes republished")
+		}
+
+		failed, err := api.failStuck(ctx)
+		if err != nil {
+			log.WithError(err).Error("failed to fail stuck solution tests")
+		} else if failed > 0 {
+			log.WithField("count", failed).
+				Warn("stuck solution tests failed")
+		}
+
+		err = api.deletePlaygroundRuns(ctx)
+		if err != nil {
+			log.WithError(err).Error("failed to delete playground runs")
+		}
+
+		failed, err = api.failStuckReferences(ctx)
+		if err != nil {
+			log.WithError(err).Error(
+				"failed to fail stuck expected stdouts generations")
+		} else if failed > 0 {
+			log.WithField("count", failed).
+				Warn("stuck expected stdouts generations failed")
+		}
+	}
+}
+
+// republishStuck enqueues codes of the stuck solution tests again with the
+// same attempt, since code can be slow rather than lost. Whichever run of
+// the attempt comes first is stored, the other one is dropped as duplicate.
+func (api *MyCodeAPI) republishStuck(ctx context.Context) (
+	republished int, err error) {
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+		update solution_test as st set processing_since = now(),
+			republishes = st.republishes + 1
+		from solution as s, test as t, exercise as e, student as stu,
+			class as c
+		where st.solution_id = s.id and st.test_id = t.id
+			and s.exercise_id = e.id and s.student_id = stu.id
+			and stu.class_id = c.id and st.id in (
+				select id from solution_test
+				where status = $1 and republishes < $2
+					and processing_since < now() - $3 * interval '1 second'
+					and not exists (
+						select 1 from code_outbox
+						where solution_test_id = solution_test.id
+					)
+				limit $4 for update skip locked
+			)
+		returning `+solutionTestCodeColumns,
+		mycode.SolutionTestStatus_processing, maxRepublishes,
+		stuckTimeout.Seconds(), reaperBatch)
+	if err != nil {
+		return 0, fmt.Errorf("update stuck solution tests in DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var cs []*mycode.Code
+
+	for rows.Next() {
+		var (
+			c                    *mycode.Code
+			studentID, teacherID int64
+		)
+
+		c, err = scanSolutionTestCode(rows, &mycode.SolutionTest{},
+			&studentID, &teacherID)
+		if err != nil {
+			return 0, err
+		}
+
+		cs = append(cs, c)
+	}
+
+	if rows.Err() != nil {
+		err = rows.Err()
+		return 0, fmt.Errorf("solution tests rows error: %w", err)
+	}
+
+	err = enqueueCodes(ctx, tx, cs)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue codes: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+
+	api.notifyOutbox()
+
+	return len(cs), nil
+}
+
+// failStuck stores internal error results of the solution tests stuck after
+// max republishes.
+func (api *MyCodeAPI) failStuck(ctx context.Context) (int, error) {
+
+	rows, err := api.db.QueryContext(ctx, `
+		select id, attempt from solution_test
+		where status = $1 and republishes >= $2
+			and processing_since < now() - $3 * interval '1 second'
+			and not exists (
+				select 1 from code_outbox
+				where solution_test_id = solution_test.id
+			)
+		limit $4
+	`, mycode.SolutionTestStatus_processing, maxRepublishes,
+		stuckTimeout.Seconds(), reaperBatch)
+	if err != nil {
+		return 0, fmt.Errorf("get stuck solution tests from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var rs []*mycode.Run
+
+	for rows.Next() {
+		r := &mycode.Run{}
+		err = rows.Scan(&r.SolutionTestId, &r.Attempt)
+		if err != nil {
+			return 0, fmt.Errorf("get solution test row from DB: %w", err)
+		}
+		rs = append(rs, r)
+	}
+
+	if rows.Err() != nil {
+		return 0, fmt.Errorf("solution tests rows error: %w", rows.Err())
+	}
+
+	for _, r := range rs {
+		err = api.FailRun(ctx, r, errStuck)
+		if err != nil {
+			return 0, fmt.Errorf("fail run: %w", err)
+		}
+	}
+
+	return len(rs), nil
+}
+
+// failStuckReferences stores errors of the tests expected stdouts pending
+// longer than stuck timeout, so exercise submissions aren't blocked forever.
+func (api *MyCodeAPI) failStuckReferences(ctx context.Context) (int, error) {
+
+	res, err := api.db.ExecContext(ctx, `
+		update test set expected_stdout_pending = false,
+			expected_stdout_error = $1
+		where expected_stdout_pending
+			and expected_stdout_pending_since < now() - $2 * interval '1 second'
+			and not exists (
+				select 1 from code_outbox where reference_test_id = test.id
+			)
+	`, errReferenceStuck.Error(), stuckTimeout.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("update stuck tests in DB: %w", err)
+	}
+
+	failed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get affected rows count: %w", err)
+	}
+
+	return int(failed), nil
+}
+
+// deletePlaygroundRuns deletes playground runs which RunCode calls stopped
+// waiting for.
+func (api *MyCodeAPI) deletePlaygroundRuns(ctx context.Context) error {
+
+	_, err := api.db.ExecContext(ctx, `
+		delete from playground_run
+		where created_at < now() - $1 * interval '1 second'
+	`, playgroundRunTimeout.Seconds())
+	if err != nil {
+		return fmt.Errorf("delete playground runs from DB: %w", err)
+	}
+
+	return nil
+}
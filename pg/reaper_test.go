@@ -0,0 +1,233 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/blob"
+)
+
+type codePublisherFunc func(c *mycode.Code) error
+
+func (f codePublisherFunc) PublishCode(c *mycode.Code) error {
+	return f(c)
+}
+
+// testAPI returns API using clean DB of MYCODE_TEST_POSTGRES_URI, test is
+// skipped without it. DB schema is dropped, so don't point it to the
+// production DB.
+func testAPI(t *testing.T, cp CodePublisher, codesRate float64,
+	codesBurst int) *MyCodeAPI {
+
+	pgURI := os.Getenv("MYCODE_TEST_POSTGRES_URI")
+	if pgURI == "" {
+		t.Skip("MYCODE_TEST_POSTGRES_URI is not set")
+	}
+
+	db, err := sql.Open("postgres", pgURI)
+	if err != nil {
+		t.Fatalf("open DB: %v", err)
+	}
+
+	_, err = db.Exec(`drop schema public cascade; create schema public`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("drop DB schema: %v", err)
+	}
+
+	bs, err := blob.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob storage: %v", err)
+	}
+
+	api, err := NewMyCodeAPI(pgURI, "secret", cp, bs, 64<<10, 1<<20,
+		codesRate, codesBurst)
+	if err != nil {
+		t.Fatalf("create API: %v", err)
+	}
+
+	t.Cleanup(func() {
+		api.Close()
+	})
+
+	err = api.Migrate()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return api
+}
+
+// addSolutionTest adds teacher, student, exercise with one test and
+// student's solution, and returns ID of the solution test.
+func addSolutionTest(t *testing.T, api *MyCodeAPI) int64 {
+
+	ctx := context.Background()
+
+	var teacherUserID, studentUserID, classID int64
+
+	err := api.db.QueryRow(`
+		with u as (
+			insert into "user" (login, password_hash)
+			values ('teacher', '') returning id
+		), t as (
+			insert into teacher (user_id, name)
+			select id, 'Teacher' from u returning id, user_id
+		)
+		insert into class (teacher_id, name)
+		select id, '1A' from t returning id, (select user_id from t)
+	`).Scan(&classID, &teacherUserID)
+	if err != nil {
+		t.Fatalf("add teacher to DB: %v", err)
+	}
+
+	err = api.db.QueryRow(`
+		with u as (
+			insert into "user" (login, password_hash)
+			values ('student', '') returning id
+		)
+		insert into student (user_id, class_id, name)
+		select id, $1, 'Student' from u returning user_id
+	`, classID).Scan(&studentUserID)
+	if err != nil {
+		t.Fatalf("add student to DB: %v", err)
+	}
+
+	teacherCtx, err := api.authorizeUser(ctx, "AddExercise", jwtTeacher,
+		teacherUserID)
+	if err != nil {
+		t.Fatalf("authorize teacher: %v", err)
+	}
+
+	studentCtx, err := api.authorizeUser(ctx, "AddSolution", jwtStudent,
+		studentUserID)
+	if err != nil {
+		t.Fatalf("authorize student: %v", err)
+	}
+
+	e, err := api.AddExercise(teacherCtx, &mycode.AddExerciseReq{
+		Title:       "A+B",
+		Description: "Add numbers.",
+		Language:    mycode.Language_python,
+	})
+	if err != nil {
+		t.Fatalf("add exercise: %v", err)
+	}
+
+	_, err = api.AddTest(teacherCtx, &mycode.AddTestReq{
+		ExerciseId:     e.ExerciseId,
+		Type:           mycode.TestType_simple,
+		Name:           "1",
+		MaxDuration:    "1s",
+		MaxMemory:      "64MB",
+		Stdin:          "1 2\n",
+		ExpectedStdout: "3\n",
+	})
+	if err != nil {
+		t.Fatalf("add test: %v", err)
+	}
+
+	_, err = api.AssignExercise(teacherCtx, &mycode.AssignExerciseReq{
+		ExerciseId: e.ExerciseId,
+		ClassId:    classID,
+	})
+	if err != nil {
+		t.Fatalf("assign exercise: %v", err)
+	}
+
+	s, err := api.AddSolution(studentCtx, &mycode.AddSolutionReq{
+		ExerciseId: e.ExerciseId,
+		Source:     "print(sum(map(int, input().split())))",
+	})
+	if err != nil {
+		t.Fatalf("add solution: %v", err)
+	}
+
+	var solutionTestID int64
+
+	err = api.db.QueryRow(`
+		select id from solution_test where solution_id = $1
+	`, s.SolutionId).Scan(&solutionTestID)
+	if err != nil {
+		t.Fatalf("get solution test from DB: %v", err)
+	}
+
+	return solutionTestID
+}
+
+func TestReaperSkipsLimitedCodes(t *testing.T) {
+
+	var published []*mycode.Code
+
+	api := testAPI(t, codePublisherFunc(func(c *mycode.Code) error {
+		published = append(published, c)
+		return nil
+	}), 0.001, 10)
+
+	ctx := context.Background()
+
+	solutionTestID := addSolutionTest(t, api)
+
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		_, err := api.db.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("exec %q: %v", query, err)
+		}
+	}
+
+	exec(`update code_bucket set tokens = 0, updated_at = now()`)
+
+	n, err := api.relayCodes(ctx)
+	if err != nil || n != 0 || len(published) != 0 {
+		t.Fatalf("relay codes = %d, %v, want code held by limiter", n, err)
+	}
+
+	exec(`
+		update solution_test
+		set processing_since = now() - interval '1 hour'
+	`)
+
+	n, err = api.republishStuck(ctx)
+	if err != nil || n != 0 {
+		t.Errorf("republish stuck = %d, %v, want 0", n, err)
+	}
+
+	exec(`update solution_test set republishes = $1`, maxRepublishes)
+
+	n, err = api.failStuck(ctx)
+	if err != nil || n != 0 {
+		t.Errorf("fail stuck = %d, %v, want 0", n, err)
+	}
+
+	exec(`update solution_test set republishes = 0`)
+	exec(`update code_bucket set tokens = 1, updated_at = now()`)
+
+	n, err = api.relayCodes(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("relay codes = %d, %v, want 1", n, err)
+	}
+
+	if len(published) != 1 || published[0].SolutionTestId != solutionTestID {
+		t.Fatalf("published codes = %v, want solution test %d code",
+			published, solutionTestID)
+	}
+
+	n, err = api.republishStuck(ctx)
+	if err != nil || n != 0 {
+		t.Errorf("republish just published = %d, %v, want 0", n, err)
+	}
+
+	exec(`
+		update solution_test
+		set processing_since = now() - interval '1 hour'
+	`)
+
+	n, err = api.republishStuck(ctx)
+	if err != nil || n != 1 {
+		t.Errorf("republish stuck = %d, %v, want 1", n, err)
+	}
+}
@@ -22,14 +22,350 @@ func init() {
 
 		Content: string("create table \"user\" (\n    id bigserial primary key,\n    login text not null unique,\n    password_hash bytea not null\n);\n\ncreate table teacher (\n    id bigserial primary key,\n    user_id bigint not null references \"user\" (id) on delete cascade,\n    name text not null\n);\n\ncreate index on teacher (user_id);\n\ncreate table class (\n    id bigserial primary key,\n    teacher_id bigint not null references teacher (id) on delete cascade,\n    name text not null\n);\n\ncreate index on class (teacher_id);\n\ncreate table student (\n    id bigserial primary key,\n    user_id bigint not null references \"user\" (id) on delete cascade,\n    class_id bigint not null references class (id) on delete cascade,\n    name text not null\n);\n\ncreate index on student (user_id);\ncreate index on student (class_id);\n\ncreate table exercise (\n    id bigserial primary key,\n    teacher_id bigint not null references teacher (id) on delete cascade,\n    title text not null,\n    description text not null,\n    language int not null,\n    estimator int not null\n);\n\ncreate index on exercise (teacher_id);\n\ncreate table test (\n    id bigserial primary key,\n    exercise_id bigint not null references exercise(id) on delete cascade,\n    type text not null,\n    name text not null,\n    max_duration text not null,\n    max_memory text not null,\n    stdin text not null,\n    expected_stdout text,\n    checker_language int,\n    checker_source text\n);\n\ncreate index on test (exercise_id);\n\ncreate table student_exercise (\n    student_id bigint not null references student (id) on delete cascade,\n    exercise_id bigint not null references exercise (id) on delete cascade,\n\n    primary key (student_id, exercise_id)\n);\n\ncreate index on student_exercise (exercise_id, student_id);\n\ncreate table solution (\n    id bigserial primary key,\n    student_id bigint not null references student (id) on delete cascade,\n    exercise_id bigint not null references exercise (id) on delete cascade,\n    source text not null,\n\n    foreign key (student_id, exercise_id) references\n        student_exercise (student_id, exercise_id) on delete cascade\n);\n\ncreate index on solution (student_id);\ncreate index on solution (exercise_id);\n\ncreate table solution_test (\n    id bigserial primary key,\n    solution_id bigint not null references solution (id) on delete cascade,\n    test_id bigint not null references test (id) on delete cascade,\n    status text not null,\n    duration text,\n    used_memory text,\n    stdout text,\n    stderr text,\n    checker_stdout text,\n    checker_stderr text,\n    fails jsonb\n);\n\ncreate index on solution_test (solution_id);\ncreate index on solution_test (test_id);\n"),
 	}
+	file4 := &embedded.EmbeddedFile{
+		Filename:    "0002_solution_grade.down.sql",
+		FileModTime: time.Unix(1792319556, 0),
+
+		Content: string("alter table solution drop column grade;\n"),
+	}
+	file5 := &embedded.EmbeddedFile{
+		Filename:    "0002_solution_grade.up.sql",
+		FileModTime: time.Unix(1792319556, 0),
+
+		Content: string("alter table solution add column grade double precision;\n"),
+	}
+	file6 := &embedded.EmbeddedFile{
+		Filename:    "0003_solution_test_compiler_output.down.sql",
+		FileModTime: time.Unix(1792319837, 0),
+
+		Content: string("alter table solution_test drop column compiler_output;\n"),
+	}
+	file7 := &embedded.EmbeddedFile{
+		Filename:    "0003_solution_test_compiler_output.up.sql",
+		FileModTime: time.Unix(1792319837, 0),
+
+		Content: string("alter table solution_test add column compiler_output text;\n"),
+	}
+	file8 := &embedded.EmbeddedFile{
+		Filename:    "0004_solution_test_verdict.down.sql",
+		FileModTime: time.Unix(1792319916, 0),
+
+		Content: string("alter table solution_test\n    drop column verdict,\n    drop column exit_code,\n    drop column signal;\n"),
+	}
+	file9 := &embedded.EmbeddedFile{
+		Filename:    "0004_solution_test_verdict.up.sql",
+		FileModTime: time.Unix(1792319916, 0),
+
+		Content: string("alter table solution_test\n    add column verdict int,\n    add column exit_code int,\n    add column signal int;\n"),
+	}
+	filea := &embedded.EmbeddedFile{
+		Filename:    "0005_solution_test_wall_duration.down.sql",
+		FileModTime: time.Unix(1792320164, 0),
+
+		Content: string("alter table solution_test\n    drop column wall_duration;\n"),
+	}
+	fileb := &embedded.EmbeddedFile{
+		Filename:    "0005_solution_test_wall_duration.up.sql",
+		FileModTime: time.Unix(1792320164, 0),
+
+		Content: string("alter table solution_test\n    add column wall_duration text;\n"),
+	}
+	filec := &embedded.EmbeddedFile{
+		Filename:    "0006_test_comparison_mode.down.sql",
+		FileModTime: time.Unix(1792320198, 0),
+
+		Content: string("alter table test\n    drop column comparison_mode,\n    drop column float_abs_epsilon,\n    drop column float_rel_epsilon;\n"),
+	}
+	filed := &embedded.EmbeddedFile{
+		Filename:    "0006_test_comparison_mode.up.sql",
+		FileModTime: time.Unix(1792320198, 0),
+
+		Content: string("alter table test\n    add column comparison_mode int not null default 0,\n    add column float_abs_epsilon double precision not null default 0,\n    add column float_rel_epsilon double precision not null default 0;\n"),
+	}
+	filee := &embedded.EmbeddedFile{
+		Filename:    "0007_solution_test_score.down.sql",
+		FileModTime: time.Unix(1792320322, 0),
+
+		Content: string("alter table solution_test\n    drop column score,\n    drop column checker_message;\n"),
+	}
+	filef := &embedded.EmbeddedFile{
+		Filename:    "0007_solution_test_score.up.sql",
+		FileModTime: time.Unix(1792320322, 0),
+
+		Content: string("alter table solution_test\n    add column score double precision,\n    add column checker_message text;\n\nupdate solution_test set score = case when status = '2' then 1 else 0 end\nwhere status <> '0';\n"),
+	}
+	fileg := &embedded.EmbeddedFile{
+		Filename:    "0008_exercise_reference_source.down.sql",
+		FileModTime: time.Unix(1792320385, 0),
+
+		Content: string("alter table test\n    drop column expected_stdout_pending,\n    drop column expected_stdout_attempt,\n    drop column expected_stdout_error;\n\nalter table exercise\n    drop column reference_source;\n"),
+	}
+	fileh := &embedded.EmbeddedFile{
+		Filename:    "0008_exercise_reference_source.up.sql",
+		FileModTime: time.Unix(1792320385, 0),
+
+		Content: string("alter table exercise\n    add column reference_source text;\n\nalter table test\n    add column expected_stdout_pending bool not null default false,\n    add column expected_stdout_attempt bigint not null default 0,\n    add column expected_stdout_error text;\n"),
+	}
+	filei := &embedded.EmbeddedFile{
+		Filename:    "0009_blobs.down.sql",
+		FileModTime: time.Unix(1792320979, 0),
+
+		Content: string("alter table solution_test\n    drop column stdout_blob,\n    drop column stderr_blob;\n\nalter table test\n    drop column stdin_blob,\n    drop column expected_stdout_blob;\n"),
+	}
+	filej := &embedded.EmbeddedFile{
+		Filename:    "0009_blobs.up.sql",
+		FileModTime: time.Unix(1792320979, 0),
+
+		Content: string("alter table test\n    add column stdin_blob text,\n    add column expected_stdout_blob text;\n\nalter table solution_test\n    add column stdout_blob text,\n    add column stderr_blob text;\n"),
+	}
+	filek := &embedded.EmbeddedFile{
+		Filename:    "0010_solution_test_attempt.down.sql",
+		FileModTime: time.Unix(1792321297, 0),
+
+		Content: string("alter table solution_test\n    drop column attempt;\n"),
+	}
+	filel := &embedded.EmbeddedFile{
+		Filename:    "0010_solution_test_attempt.up.sql",
+		FileModTime: time.Unix(1792321297, 0),
+
+		Content: string("alter table solution_test\n    add column attempt bigint not null default 0;\n"),
+	}
+	filem := &embedded.EmbeddedFile{
+		Filename:    "0011_student_exercise_window.down.sql",
+		FileModTime: time.Unix(1792321466, 0),
+
+		Content: string("alter table solution\n    drop column late;\n\nalter table student_exercise\n    drop column opens_at,\n    drop column closes_at,\n    drop column late_policy,\n    drop column late_penalty;\n"),
+	}
+	filen := &embedded.EmbeddedFile{
+		Filename:    "0011_student_exercise_window.up.sql",
+		FileModTime: time.Unix(1792321466, 0),
+
+		Content: string("alter table student_exercise\n    add column opens_at timestamptz,\n    add column closes_at timestamptz,\n    add column late_policy int not null default 0,\n    add column late_penalty double precision not null default 0;\n\nalter table solution\n    add column late bool not null default false;\n"),
+	}
+	fileo := &embedded.EmbeddedFile{
+		Filename:    "0012_submission_limits.down.sql",
+		FileModTime: time.Unix(1792321543, 0),
+
+		Content: string("alter table solution\n    drop column created_at;\n\nalter table exercise\n    drop column max_attempts,\n    drop column submission_cooldown;\n"),
+	}
+	filep := &embedded.EmbeddedFile{
+		Filename:    "0012_submission_limits.up.sql",
+		FileModTime: time.Unix(1792321543, 0),
+
+		Content: string("alter table exercise\n    add column max_attempts int not null default 0,\n    add column submission_cooldown text;\n\nalter table solution\n    add column created_at timestamptz not null default now();\n"),
+	}
+	fileq := &embedded.EmbeddedFile{
+		Filename:    "0013_similarity.down.sql",
+		FileModTime: time.Unix(1792321647, 0),
+
+		Content: string("drop table similarity_pair;\n\nalter table exercise\n    drop column similarity_pending;\n"),
+	}
+	filer := &embedded.EmbeddedFile{
+		Filename:    "0013_similarity.up.sql",
+		FileModTime: time.Unix(1792321647, 0),
+
+		Content: string("alter table exercise\n    add column similarity_pending bool not null default false;\n\nupdate exercise as e set similarity_pending = true\nwhere exists (select 1 from solution where exercise_id = e.id);\n\ncreate table similarity_pair (\n    exercise_id bigint not null references exercise (id) on delete cascade,\n    solution_a_id bigint not null references solution (id) on delete cascade,\n    solution_b_id bigint not null references solution (id) on delete cascade,\n    score double precision not null,\n    fragments jsonb not null,\n\n    primary key (solution_a_id, solution_b_id)\n);\n\ncreate index on similarity_pair (exercise_id);\n"),
+	}
+	files := &embedded.EmbeddedFile{
+		Filename:    "0014_solution_test_processing.down.sql",
+		FileModTime: time.Unix(1792321783, 0),
+
+		Content: string("alter table solution_test\n    drop column processing_since,\n    drop column republishes;\n"),
+	}
+	filet := &embedded.EmbeddedFile{
+		Filename:    "0014_solution_test_processing.up.sql",
+		FileModTime: time.Unix(1792321783, 0),
+
+		Content: string("alter table solution_test\n    add column processing_since timestamptz not null default now(),\n    add column republishes int not null default 0;\n\ncreate index on solution_test (processing_since) where status = '0';\n"),
+	}
+	fileu := &embedded.EmbeddedFile{
+		Filename:    "0015_code_outbox.down.sql",
+		FileModTime: time.Unix(1792321839, 0),
+
+		Content: string("drop table code_outbox;\n"),
+	}
+	filev := &embedded.EmbeddedFile{
+		Filename:    "0015_code_outbox.up.sql",
+		FileModTime: time.Unix(1792321839, 0),
+
+		Content: string("create table code_outbox (\n    id bigserial primary key,\n    code jsonb not null\n);\n"),
+	}
+	filew := &embedded.EmbeddedFile{
+		Filename:    "0016_events_ticket.down.sql",
+		FileModTime: time.Unix(1792322552, 0),
+
+		Content: string("drop table events_ticket;\n"),
+	}
+	filex := &embedded.EmbeddedFile{
+		Filename:    "0016_events_ticket.up.sql",
+		FileModTime: time.Unix(1792322552, 0),
+
+		Content: string("create table events_ticket (\n    ticket text primary key,\n    user_id bigint not null references \"user\" (id) on delete cascade,\n    user_role text not null,\n    expires_at timestamptz not null\n);\n"),
+	}
+	filey := &embedded.EmbeddedFile{
+		Filename:    "0017_test_checker_protocol.down.sql",
+		FileModTime: time.Unix(1792322673, 0),
+
+		Content: string("alter table test\n    drop column checker_protocol;\n"),
+	}
+	filez := &embedded.EmbeddedFile{
+		Filename:    "0017_test_checker_protocol.up.sql",
+		FileModTime: time.Unix(1792322696, 0),
+
+		Content: string("alter table test\n    add column checker_protocol int not null default 0;\n"),
+	}
+	file10 := &embedded.EmbeddedFile{
+		Filename:    "0018_test_expected_stdout_pending_since.down.sql",
+		FileModTime: time.Unix(1792322732, 0),
+
+		Content: string("alter table test\n    drop column expected_stdout_pending_since;\n"),
+	}
+	file11 := &embedded.EmbeddedFile{
+		Filename:    "0018_test_expected_stdout_pending_since.up.sql",
+		FileModTime: time.Unix(1792322732, 0),
+
+		Content: string("alter table test\n    add column expected_stdout_pending_since timestamptz;\n\nupdate test set expected_stdout_pending_since = now()\nwhere expected_stdout_pending;\n\ncreate index on test (expected_stdout_pending_since)\nwhere expected_stdout_pending;\n"),
+	}
+	file12 := &embedded.EmbeddedFile{
+		Filename:    "0019_playground_run.down.sql",
+		FileModTime: time.Unix(1792322763, 0),
+
+		Content: string("drop table playground_run;\n"),
+	}
+	file13 := &embedded.EmbeddedFile{
+		Filename:    "0019_playground_run.up.sql",
+		FileModTime: time.Unix(1792322763, 0),
+
+		Content: string("create table playground_run (\n    id text primary key,\n    run jsonb not null,\n    created_at timestamptz not null default now()\n);\n"),
+	}
+	file14 := &embedded.EmbeddedFile{
+		Filename:    "0020_blob_indexes.down.sql",
+		FileModTime: time.Unix(1792323152, 0),
+
+		Content: string("drop index test_stdin_blob_idx;\ndrop index test_expected_stdout_blob_idx;\n\ndrop index solution_test_stdout_blob_idx;\ndrop index solution_test_stderr_blob_idx;\n"),
+	}
+	file15 := &embedded.EmbeddedFile{
+		Filename:    "0020_blob_indexes.up.sql",
+		FileModTime: time.Unix(1792323152, 0),
+
+		Content: string("create index on test (stdin_blob) where stdin_blob is not null;\ncreate index on test (expected_stdout_blob)\nwhere expected_stdout_blob is not null;\n\ncreate index on solution_test (stdout_blob) where stdout_blob is not null;\ncreate index on solution_test (stderr_blob) where stderr_blob is not null;\n"),
+	}
+	file16 := &embedded.EmbeddedFile{
+		Filename:    "0021_code_bucket.down.sql",
+		FileModTime: time.Unix(1792323363, 0),
+
+		Content: string("drop table code_bucket;\n"),
+	}
+	file17 := &embedded.EmbeddedFile{
+		Filename:    "0021_code_bucket.up.sql",
+		FileModTime: time.Unix(1792323363, 0),
+
+		Content: string("create table code_bucket (\n    tokens double precision not null,\n    updated_at timestamptz not null\n);\n\ninsert into code_bucket (tokens, updated_at) values (0, now());\n"),
+	}
+	file18 := &embedded.EmbeddedFile{
+		Filename:    "0022_exercise_similarity_solution.down.sql",
+		FileModTime: time.Unix(1792323413, 0),
+
+		Content: string("alter table exercise\n    add column similarity_pending bool not null default false;\n\nupdate exercise as e set similarity_pending = true\nwhere exists (\n    select 1 from solution\n    where exercise_id = e.id and id > e.similarity_solution_id\n);\n\nalter table exercise\n    drop column similarity_solution_id;\n"),
+	}
+	file19 := &embedded.EmbeddedFile{
+		Filename:    "0022_exercise_similarity_solution.up.sql",
+		FileModTime: time.Unix(1792323413, 0),
+
+		Content: string("alter table exercise\n    add column similarity_solution_id bigint not null default 0;\n\nupdate exercise as e set similarity_solution_id = coalesce((\n    select max(id) from solution where exercise_id = e.id\n), 0)\nwhere not e.similarity_pending;\n\nalter table exercise\n    drop column similarity_pending;\n"),
+	}
+	file1a := &embedded.EmbeddedFile{
+		Filename:    "0023_code_outbox_class.down.sql",
+		FileModTime: time.Unix(1792325054, 0),
+
+		Content: string("alter table code_outbox drop column class_id;\n"),
+	}
+	file1b := &embedded.EmbeddedFile{
+		Filename:    "0023_code_outbox_class.up.sql",
+		FileModTime: time.Unix(1792325054, 0),
+
+		Content: string("alter table code_outbox add column class_id bigint;\n\ncreate index on code_outbox (class_id, id);\n"),
+	}
+	file1c := &embedded.EmbeddedFile{
+		Filename:    "0024_code_outbox_test.down.sql",
+		FileModTime: time.Unix(1792325100, 0),
+
+		Content: string("alter table code_outbox\n    drop column solution_test_id,\n    drop column reference_test_id;\n"),
+	}
+	file1d := &embedded.EmbeddedFile{
+		Filename:    "0024_code_outbox_test.up.sql",
+		FileModTime: time.Unix(1792325100, 0),
+
+		Content: string("alter table code_outbox\n    add column solution_test_id bigint,\n    add column reference_test_id bigint;\n\nupdate code_outbox set\n    solution_test_id = coalesce(code->>'solutionTestId',\n        code->>'solution_test_id')::bigint,\n    reference_test_id = coalesce(code->>'referenceTestId',\n        code->>'reference_test_id')::bigint;\n\ncreate index on code_outbox (solution_test_id);\ncreate index on code_outbox (reference_test_id);\n"),
+	}
+	file1e := &embedded.EmbeddedFile{
+		Filename:    "0025_code_outbox_dead.down.sql",
+		FileModTime: time.Unix(1792325205, 0),
+
+		Content: string("drop table code_outbox_dead;\n"),
+	}
+	file1f := &embedded.EmbeddedFile{
+		Filename:    "0025_code_outbox_dead.up.sql",
+		FileModTime: time.Unix(1792325205, 0),
+
+		Content: string("create table code_outbox_dead (\n    id bigint primary key,\n    code jsonb not null,\n    error text not null,\n    created_at timestamptz not null default now()\n);\n"),
+	}
 
 	// define dirs
 	dir1 := &embedded.EmbeddedDir{
 		Filename:   "",
-		DirModTime: time.Unix(1602947543, 0),
+		DirModTime: time.Unix(1792325205, 0),
 		ChildFiles: []*embedded.EmbeddedFile{
-			file2, // "0001_init.down.sql"
-			file3, // "0001_init.up.sql"
+			file2,  // "0001_init.down.sql"
+			file3,  // "0001_init.up.sql"
+			file4,  // "0002_solution_grade.down.sql"
+			file5,  // "0002_solution_grade.up.sql"
+			file6,  // "0003_solution_test_compiler_output.down.sql"
+			file7,  // "0003_solution_test_compiler_output.up.sql"
+			file8,  // "0004_solution_test_verdict.down.sql"
+			file9,  // "0004_solution_test_verdict.up.sql"
+			filea,  // "0005_solution_test_wall_duration.down.sql"
+			fileb,  // "0005_solution_test_wall_duration.up.sql"
+			filec,  // "0006_test_comparison_mode.down.sql"
+			filed,  // "0006_test_comparison_mode.up.sql"
+			filee,  // "0007_solution_test_score.down.sql"
+			filef,  // "0007_solution_test_score.up.sql"
+			fileg,  // "0008_exercise_reference_source.down.sql"
+			fileh,  // "0008_exercise_reference_source.up.sql"
+			filei,  // "0009_blobs.down.sql"
+			filej,  // "0009_blobs.up.sql"
+			filek,  // "0010_solution_test_attempt.down.sql"
+			filel,  // "0010_solution_test_attempt.up.sql"
+			filem,  // "0011_student_exercise_window.down.sql"
+			filen,  // "0011_student_exercise_window.up.sql"
+			fileo,  // "0012_submission_limits.down.sql"
+			filep,  // "0012_submission_limits.up.sql"
+			fileq,  // "0013_similarity.down.sql"
+			filer,  // "0013_similarity.up.sql"
+			files,  // "0014_solution_test_processing.down.sql"
+			filet,  // "0014_solution_test_processing.up.sql"
+			fileu,  // "0015_code_outbox.down.sql"
+			filev,  // "0015_code_outbox.up.sql"
+			filew,  // "0016_events_ticket.down.sql"
+			filex,  // "0016_events_ticket.up.sql"
+			filey,  // "0017_test_checker_protocol.down.sql"
+			filez,  // "0017_test_checker_protocol.up.sql"
+			file10, // "0018_test_expected_stdout_pending_since.down.sql"
+			file11, // "0018_test_expected_stdout_pending_since.up.sql"
+			file12, // "0019_playground_run.down.sql"
+			file13, // "0019_playground_run.up.sql"
+			file14, // "0020_blob_indexes.down.sql"
+			file15, // "0020_blob_indexes.up.sql"
+			file16, // "0021_code_bucket.down.sql"
+			file17, // "0021_code_bucket.up.sql"
+			file18, // "0022_exercise_similarity_solution.down.sql"
+			file19, // "0022_exercise_similarity_solution.up.sql"
+			file1a, // "0023_code_outbox_class.down.sql"
+			file1b, // "0023_code_outbox_class.up.sql"
+			file1c, // "0024_code_outbox_test.down.sql"
+			file1d, // "0024_code_outbox_test.up.sql"
+			file1e, // "0025_code_outbox_dead.down.sql"
+			file1f, // "0025_code_outbox_dead.up.sql"
 
 		},
 	}
@@ -40,13 +376,61 @@ func init() {
 	// register embeddedBox
 	embedded.RegisterEmbeddedBox(`migrations`, &embedded.EmbeddedBox{
 		Name: `migrations`,
-		Time: time.Unix(1602947543, 0),
+		Time: time.Unix(1792325205, 0),
 		Dirs: map[string]*embedded.EmbeddedDir{
 			"": dir1,
 		},
 		Files: map[string]*embedded.EmbeddedFile{
-			"0001_init.down.sql": file2,
-			"0001_init.up.sql":   file3,
+			"0001_init.down.sql":                               file2,
+			"0001_init.up.sql":                                 file3,
+			"0002_solution_grade.down.sql":                     file4,
+			"0002_solution_grade.up.sql":                       file5,
+			"0003_solution_test_compiler_output.down.sql":      file6,
+			"0003_solution_test_compiler_output.up.sql":        file7,
+			"0004_solution_test_verdict.down.sql":              file8,
+			"0004_solution_test_verdict.up.sql":                file9,
+			"0005_solution_test_wall_duration.down.sql":        filea,
+			"0005_solution_test_wall_duration.up.sql":          fileb,
+			"0006_test_comparison_mode.down.sql":               filec,
+			"0006_test_comparison_mode.up.sql":                 filed,
+			"0007_solution_test_score.down.sql":                filee,
+			"0007_solution_test_score.up.sql":                  filef,
+			"0008_exercise_reference_source.down.sql":          fileg,
+			"0008_exercise_reference_source.up.sql":            fileh,
+			"0009_blobs.down.sql":                              filei,
+			"0009_blobs.up.sql":                                filej,
+			"0010_solution_test_attempt.down.sql":              filek,
+			"0010_solution_test_attempt.up.sql":                filel,
+			"0011_student_exercise_window.down.sql":            filem,
+			"0011_student_exercise_window.up.sql":              filen,
+			"0012_submission_limits.down.sql":                  fileo,
+			"0012_submission_limits.up.sql":                    filep,
+			"0013_similarity.down.sql":                         fileq,
+			"0013_similarity.up.sql":                           filer,
+			"0014_solution_test_processing.down.sql":           files,
+			"0014_solution_test_processing.up.sql":             filet,
+			"0015_code_outbox.down.sql":                        fileu,
+			"0015_code_outbox.up.sql":                          filev,
+			"0016_events_ticket.down.sql":                      filew,
+			"0016_events_ticket.up.sql":                        filex,
+			"0017_test_checker_protocol.down.sql":              filey,
+			"0017_test_checker_protocol.up.sql":                filez,
+			"0018_test_expected_stdout_pending_since.down.sql": file10,
+			"0018_test_expected_stdout_pending_since.up.sql":   file11,
+			"0019_playground_run.down.sql":                     file12,
+			"0019_playground_run.up.sql":                       file13,
+			"0020_blob_indexes.down.sql":                       file14,
+			"0020_blob_indexes.up.sql":                         file15,
+			"0021_code_bucket.down.sql":                        file16,
+			"0021_code_bucket.up.sql":                          file17,
+			"0022_exercise_similarity_solution.down.sql":       file18,
+			"0022_exercise_similarity_solution.up.sql":         file19,
+			"0023_code_outbox_class.down.sql":                  file1a,
+			"0023_code_outbox_class.up.sql":                    file1b,
+			"0024_code_outbox_test.down.sql":                   file1c,
+			"0024_code_outbox_test.up.sql":                     file1d,
+			"0025_code_outbox_dead.down.sql":                   file1e,
+			"0025_code_outbox_dead.up.sql":                     file1f,
 		},
 	})
 }
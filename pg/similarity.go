@@ -0,0 +1,224 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/mycode"
+	"github.com/dimuls/mycode/similarity"
+)
+
+const (
+	similarityInterval = time.Minute
+
+	// similarityMinScore is the minimal score of the pairs stored to the
+	// report, less similar solutions aren't suspicious.
+	similarityMinScore = 0.3
+)
+
+// runSimilarity periodically analyzes similarity of solutions of exercises
+// which got new solutions, until API is closed.
+func (api *MyCodeAPI) runSimilarity() {
+	defer api.wg.Done()
+
+	log := api.log.WithField("worker", "similarity")
+
+	t := time.NewTicker(similarityInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-api.stop:
+			return
+		case <-t.C:
+		}
+
+		for {
+			select {
+			case <-api.stop:
+				return
+			default:
+			}
+
+			found, err := api.analyzeNextSimilarity(context.Background())
+			if err != nil {
+				log.WithError(err).Error("failed to analyze similarity")
+				break
+			}
+
+			if !found {
+				break
+			}
+		}
+	}
+}
+
+type similaritySolution struct {
+	id, studentID, classID int64
+	fingerprints           []similarity.Fingerprint
+}
+
+// analyzeNextSimilarity analyzes similarity of the exercise which got
+// solutions after the last analysis. Exercise is locked until the analysis
+// is stored, so the other API instances skip it, while solutions can still
+// be added.
+func (api *MyCodeAPI) analyzeNextSimilarity(ctx context.Context) (
+	found bool, err error) {
+
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err2 := tx.Rollback()
+			if err2 != nil {
+				err = fmt.Errorf("%w, failed to rollback: %v", err, err2)
+			}
+		}
+	}()
+
+	var exerciseID int64
+
+	err = tx.QueryRowContext(ctx, `
+		select e.id from exercise as e
+		where exists (
+			select 1 from solution as s
+			where s.exercise_id = e.id and s.id > e.similarity_solution_id
+		)
+		limit 1 for no key update of e skip locked
+	`).Scan(&exerciseID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = tx.Rollback()
+			if err != nil {
+				return false, fmt.Errorf("rollback tx: %w", err)
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("get next similarity exercise from DB: %w",
+			err)
+	}
+
+	err = analyzeSimilarity(ctx, tx, exerciseID)
+	if err != nil {
+		return false, fmt.Errorf("analyze exercise %d similarity: %w",
+			exerciseID, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return true, nil
+}
+
+// analyzeSimilarity compares the last solutions of the exercise students
+// from the same class pairwise and replaces the exercise similarity report
+// in the transaction. Last analyzed solution is stored, so the exercise
+// isn't pending until it gets new solutions.
+func analyzeSimilarity(ctx context.Context, tx *sql.Tx,
+	exerciseID int64) error {
+
+	rows, err := tx.QueryContext(ctx, `
+		select distinct on (s.student_id) s.id, s.student_id, stu.class_id,
+			e.language, s.source
+		from solution as s
+		join exercise as e on s.exercise_id = e.id
+		join student as stu on s.student_id = stu.id
+		where s.exercise_id = $1
+		order by s.student_id, s.id desc
+	`, exerciseID)
+	if err != nil {
+		return fmt.Errorf("get solutions from DB: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		ss             []similaritySolution
+		lastSolutionID int64
+	)
+
+	for rows.Next() {
+		var (
+			s        similaritySolution
+			language mycode.Language
+			source   string
+		)
+		err = rows.Scan(&s.id, &s.studentID, &s.classID, &language, &source)
+		if err != nil {
+			return fmt.Errorf("get solution row from DB: %w", err)
+		}
+		s.fingerprints = similarity.Fingerprints(language, source)
+		ss = append(ss, s)
+		if s.id > lastSolutionID {
+			lastSolutionID = s.id
+		}
+	}
+
+	if rows.Err() != nil {
+		return fmt.Errorf("solutions rows error: %w", rows.Err())
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		delete from similarity_pair where exercise_id = $1
+	`, exerciseID)
+	if err != nil {
+		return fmt.Errorf("delete similarity pairs from DB: %w", err)
+	}
+
+	for i := range ss {
+		for j := i + 1; j < len(ss); j++ {
+			a, b := ss[i], ss[j]
+
+			if a.classID != b.classID {
+				continue
+			}
+
+			score, fragments := similarity.Compare(a.fingerprints,
+				b.fingerprints)
+			if score < similarityMinScore {
+				continue
+			}
+
+			fs := make([]*mycode.SimilarityFragment, 0, len(fragments))
+			for _, f := range fragments {
+				fs = append(fs, &mycode.SimilarityFragment{
+					AStartLine: int64(f.AStartLine),
+					AEndLine:   int64(f.AEndLine),
+					BStartLine: int64(f.BStartLine),
+					BEndLine:   int64(f.BEndLine),
+				})
+			}
+
+			fsJSON, err := json.Marshal(fs)
+			if err != nil {
+				return fmt.Errorf("JSON marshal fragments: %w", err)
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				insert into similarity_pair (exercise_id, solution_a_id,
+					solution_b_id, score, fragments)
+				values ($1, $2, $3, $4, $5)
+			`, exerciseID, a.id, b.id, score, string(fsJSON))
+			if err != nil {
+				return fmt.Errorf("add similarity pair to DB: %w", err)
+			}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		update exercise set similarity_solution_id = $2 where id = $1
+	`, exerciseID, lastSolutionID)
+	if err != nil {
+		return fmt.Errorf("update exercise in DB: %w", err)
+	}
+
+	return nil
+}
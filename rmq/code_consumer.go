@@ -15,6 +15,8 @@ import (
 
 type CodeHandler interface {
 	HandleCode(context.Context, *mycode.Code) error
+	// FailCode is called when code handling failed after all retries.
+	FailCode(context.Context, *mycode.Code, error) error
 }
 
 type CodeConsumer struct {
@@ -52,6 +54,11 @@ func NewCodeConsumer(rmqURI string, qos int, ch CodeHandler) (
 		return nil, fmt.Errorf("declare queue: %w", err)
 	}
 
+	err = declareRetryQueues(cc.channel, codeQueue)
+	if err != nil {
+		return nil, err
+	}
+
 	err = cc.channel.Qos(qos, 0, false)
 	if err != nil {
 		return nil, fmt.Errorf("set qos: %w", err)
@@ -92,29 +99,54 @@ func (cc *CodeConsumer) handleMsg(ctx context.Context, msg amqp.Delivery) {
 
 	err := proto.Unmarshal(msg.Body, code)
 	if err != nil {
-		logrus.WithError(err).Error(
-			"failed to JSON unmarshal code: %w", err)
-		err = msg.Nack(false, false)
+		cc.log.WithError(err).Error("failed to proto unmarshal code")
+		err = bury(cc.channel, codeQueue, msg, err)
 		if err != nil {
-			cc.log.WithError(err).Error("failed to nack")
+			cc.log.WithError(err).Error("failed to bury")
 		}
 		return
 	}
 
 	log := cc.log.WithField("solution_test_id", code.SolutionTestId)
 
-	err = cc.codeHandler.HandleCode(ctx, code)
-	if err != nil {
-		err = msg.Nack(false, false)
+	handleErr := cc.codeHandler.HandleCode(ctx, code)
+	if handleErr == nil {
+		err = msg.Ack(false)
+		if err != nil {
+			log.WithError(err).Error("failed to ack")
+		}
+		return
+	}
+
+	// Handling is interrupted by consumer closing, code is handled
+	// again later without counting the retry.
+	if ctx.Err() != nil {
+		err = msg.Nack(false, true)
 		if err != nil {
 			log.WithError(err).Error("failed to nack")
 		}
 		return
 	}
 
-	err = msg.Ack(false)
+	log = log.WithField("retry", retryCount(msg))
+
+	log.WithError(handleErr).Warn("failed to handle code")
+
+	buried, err := retryOrBury(cc.channel, codeQueue, msg, handleErr)
+	if err != nil {
+		log.WithError(err).Error("failed to retry code")
+		return
+	}
+
+	if !buried {
+		return
+	}
+
+	log.Error("code retries exhausted, buried in dead queue")
+
+	err = cc.codeHandler.FailCode(ctx, code, handleErr)
 	if err != nil {
-		log.WithError(err).Error("failed to ack")
+		log.WithError(err).Error("failed to handle code failure")
 	}
 }
 
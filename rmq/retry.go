@@ -0,0 +1,139 @@
+package rmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/isayme/go-amqp-reconnect/rabbitmq"
+	"github.com/streadway/amqp"
+)
+
+const (
+	retryCountHeader = "x-retry-count"
+	errorHeader      = "x-error"
+)
+
+// retryDelays are delays before the message retries. Message failed after
+// the last retry is moved to the dead queue.
+var retryDelays = []time.Duration{
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+func retryQueue(queue string, retry int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, retry)
+}
+
+func deadQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// declareRetryQueues declares the queue retry queues and dead queue. Retry
+// queues have no consumers, their messages expire after the retry delay and
+// are dead lettered back to the queue by the default exchange.
+func declareRetryQueues(ch *rabbitmq.Channel, queue string) error {
+
+	for i, d := range retryDelays {
+		_, err := ch.QueueDeclare(retryQueue(queue, i+1), true, false,
+			false, false, amqp.Table{
+				"x-message-ttl":             int32(d / time.Millisecond),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			})
+		if err != nil {
+			return fmt.Errorf("declare retry queue: %w", err)
+		}
+	}
+
+	_, err := ch.QueueDeclare(deadQueue(queue), true, false,
+		false, false, nil)
+	if err != nil {
+		return fmt.Errorf("declare dead queue: %w", err)
+	}
+
+	return nil
+}
+
+func retryCount(msg amqp.Delivery) int {
+	switch c := msg.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(c)
+	case int64:
+		return int(c)
+	default:
+		return 0
+	}
+}
+
+// retryOrBury republishes failed message to the retry queue of its next
+// retry or, when retries are exhausted, buries it in the dead queue. It
+// returns true if the message is buried.
+func retryOrBury(ch *rabbitmq.Channel, queue string, msg amqp.Delivery,
+	cause error) (bool, error) {
+
+	retry := retryCount(msg) + 1
+
+	if retry > len(retryDelays) {
+		return true, bury(ch, queue, msg, cause)
+	}
+
+	err := republish(ch, retryQueue(queue, retry), msg, amqp.Table{
+		retryCountHeader: int32(retry),
+	})
+	if err != nil {
+		return false, fmt.Errorf("publish to retry queue: %w", err)
+	}
+
+	return false, nil
+}
+
+// bury moves message to the dead queue with the cause of its failure.
+func bury(ch *rabbitmq.Channel, queue string, msg amqp.Delivery,
+	cause error) error {
+
+	err := republish(ch, deadQueue(queue), msg, amqp.Table{
+		errorHeader: cause.Error(),
+	})
+	if err != nil {
+		return fmt.Errorf("publish to dead queue: %w", err)
+	}
+
+	return nil
+}
+
+// republish publishes message copy with merged headers to the queue and
+// acks the message. Message is nacked with requeue if publishing failed,
+// so it is never lost.
+func republish(ch *rabbitmq.Channel, queue string, msg amqp.Delivery,
+	headers amqp.Table) error {
+
+	hs := amqp.Table{}
+	for k, v := range msg.Headers {
+		hs[k] = v
+	}
+	for k, v := range headers {
+		hs[k] = v
+	}
+
+	err := ch.Publish("", queue, false, false, amqp.Publishing{
+		Headers:      hs,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		nackErr := msg.Nack(false, true)
+		if nackErr != nil {
+			return fmt.Errorf("%w, failed to nack: %v", err, nackErr)
+		}
+		return err
+	}
+
+	err = msg.Ack(false)
+	if err != nil {
+		return fmt.Errorf("ack: %w", err)
+	}
+
+	return nil
+}
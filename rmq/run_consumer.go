@@ -15,6 +15,8 @@ import (
 
 type RunHandler interface {
 	HandleRun(context.Context, *mycode.Run) error
+	// FailRun is called when run handling failed after all retries.
+	FailRun(context.Context, *mycode.Run, error) error
 }
 
 type RunConsumer struct {
@@ -52,6 +54,11 @@ func NewRunConsumer(rmqURI string, qos int, ch RunHandler) (
 		return nil, fmt.Errorf("declare queue: %w", err)
 	}
 
+	err = declareRetryQueues(cc.channel, runQueue)
+	if err != nil {
+		return nil, err
+	}
+
 	err = cc.channel.Qos(qos, 0, false)
 	if err != nil {
 		return nil, fmt.Errorf("set qos: %w", err)
@@ -92,29 +99,54 @@ func (cc *RunConsumer) handleMsg(ctx context.Context, msg amqp.Delivery) {
 
 	err := proto.Unmarshal(msg.Body, run)
 	if err != nil {
-		logrus.WithError(err).Error(
-			"failed to JSON unmarshal run: %w", err)
-		err = msg.Nack(false, false)
+		cc.log.WithError(err).Error("failed to proto unmarshal run")
+		err = bury(cc.channel, runQueue, msg, err)
 		if err != nil {
-			cc.log.WithError(err).Error("failed to nack")
+			cc.log.WithError(err).Error("failed to bury")
 		}
 		return
 	}
 
 	log := cc.log.WithField("solution_test_id", run.SolutionTestId)
 
-	err = cc.runHandler.HandleRun(ctx, run)
-	if err != nil {
-		err = msg.Nack(false, false)
+	handleErr := cc.runHandler.HandleRun(ctx, run)
+	if handleErr == nil {
+		err = msg.Ack(false)
+		if err != nil {
+			log.WithError(err).Error("failed to ack")
+		}
+		return
+	}
+
+	// Handling is interrupted by consumer closing, run is handled
+	// again later without counting the retry.
+	if ctx.Err() != nil {
+		err = msg.Nack(false, true)
 		if err != nil {
 			log.WithError(err).Error("failed to nack")
 		}
 		return
 	}
 
-	err = msg.Ack(false)
+	log = log.WithField("retry", retryCount(msg))
+
+	log.WithError(handleErr).Warn("failed to handle run")
+
+	buried, err := retryOrBury(cc.channel, runQueue, msg, handleErr)
+	if err != nil {
+		log.WithError(err).Error("failed to retry run")
+		return
+	}
+
+	if !buried {
+		return
+	}
+
+	log.Error("run retries exhausted, buried in dead queue")
+
+	err = cc.runHandler.FailRun(ctx, run, handleErr)
 	if err != nil {
-		log.WithError(err).Error("failed to ack")
+		log.WithError(err).Error("failed to handle run failure")
 	}
 }
 
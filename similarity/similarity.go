@@ -0,0 +1,182 @@
+// Package similarity finds copied code in solutions. Sources are tokenized
+// and fingerprinted with winnowing of tokens k-grams hashes, as in MOSS,
+// see "Winnowing: Local Algorithms for Document Fingerprinting" by
+// Schleimer, Wilkerson and Aiken. Solutions sharing many fingerprints are
+// similar.
+package similarity
+
+import (
+	"hash/fnv"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	// k is tokens k-gram size. Matches shorter than k tokens are not
+	// detected.
+	k = 5
+
+	// w is winnowing window size. Matches at least w+k-1 tokens long are
+	// always detected.
+	w = 4
+)
+
+// Fingerprint is the hash of tokens k-gram starting at StartLine and ending
+// at EndLine of the source.
+type Fingerprint struct {
+	Hash      uint64
+	StartLine int
+	EndLine   int
+}
+
+// Fragment is the matched lines range of sources A and B.
+type Fragment struct {
+	AStartLine int
+	AEndLine   int
+	BStartLine int
+	BEndLine   int
+}
+
+// Fingerprints returns source fingerprints selected by winnowing.
+func Fingerprints(l mycode.Language, source string) []Fingerprint {
+
+	ts := tokenize(l, source)
+
+	if len(ts) < k {
+		return nil
+	}
+
+	hs := make([]uint64, len(ts)-k+1)
+
+	for i := range hs {
+		h := fnv.New64a()
+		for _, t := range ts[i : i+k] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hs[i] = h.Sum64()
+	}
+
+	var (
+		fps  []Fingerprint
+		last = -1
+	)
+
+	add := func(i int) {
+		if i == last {
+			return
+		}
+		last = i
+		fps = append(fps, Fingerprint{
+			Hash:      hs[i],
+			StartLine: ts[i].line,
+			EndLine:   ts[i+k-1].line,
+		})
+	}
+
+	if len(hs) < w {
+		add(minIndex(hs, 0, len(hs)))
+		return fps
+	}
+
+	for i := 0; i+w <= len(hs); i++ {
+		add(minIndex(hs, i, i+w))
+	}
+
+	return fps
+}
+
+// minIndex returns index of the rightmost minimal hash in hs[from:to].
+func minIndex(hs []uint64, from, to int) int {
+	m := from
+	for i := from + 1; i < to; i++ {
+		if hs[i] <= hs[m] {
+			m = i
+		}
+	}
+	return m
+}
+
+// Compare returns similarity score of sources from 0 to 1 and their matched
+// fragments. Score is the share of distinct fingerprints the sources
+// share, counted against the smaller source.
+func Compare(a, b []Fingerprint) (float64, []Fragment) {
+
+	aHashes := distinct(a)
+	bHashes := distinct(b)
+
+	if len(aHashes) == 0 || len(bHashes) == 0 {
+		return 0, nil
+	}
+
+	bFirst := map[uint64]Fingerprint{}
+	for _, fp := range b {
+		if _, exists := bFirst[fp.Hash]; !exists {
+			bFirst[fp.Hash] = fp
+		}
+	}
+
+	shared := 0
+	for h := range aHashes {
+		if bHashes[h] {
+			shared++
+		}
+	}
+
+	var fs []Fragment
+
+	for _, afp := range a {
+		bfp, exists := bFirst[afp.Hash]
+		if !exists {
+			continue
+		}
+
+		if n := len(fs); n > 0 {
+			f := &fs[n-1]
+			if afp.StartLine <= f.AEndLine+1 &&
+				bfp.StartLine <= f.BEndLine+1 &&
+				bfp.EndLine >= f.BStartLine {
+				f.AEndLine = max(f.AEndLine, afp.EndLine)
+				f.BStartLine = min(f.BStartLine, bfp.StartLine)
+				f.BEndLine = max(f.BEndLine, bfp.EndLine)
+				continue
+			}
+		}
+
+		fs = append(fs, Fragment{
+			AStartLine: afp.StartLine,
+			AEndLine:   afp.EndLine,
+			BStartLine: bfp.StartLine,
+			BEndLine:   bfp.EndLine,
+		})
+	}
+
+	smaller := len(aHashes)
+	if len(bHashes) < smaller {
+		smaller = len(bHashes)
+	}
+
+	return float64(shared) / float64(smaller), fs
+}
+
+func distinct(fps []Fingerprint) map[uint64]bool {
+	hs := map[uint64]bool{}
+	for _, fp := range fps {
+		hs[fp.Hash] = true
+	}
+	return hs
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,219 @@
+package similarity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/mycode"
+)
+
+const cSum = `#include <stdio.h>
+
+int main() {
+    int n, sum = 0;
+    scanf("%d", &n);
+    for (int i = 0; i < n; i++) {
+        int x;
+        scanf("%d", &x);
+        sum += x;
+    }
+    printf("%d\n", sum);
+    return 0;
+}
+`
+
+func TestCompare(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		language  mycode.Language
+		a, b      string
+		wantScore float64
+		// wantMaxScore is checked instead of wantScore if set.
+		wantMaxScore float64
+	}{{
+		name:      "same",
+		language:  mycode.Language_c,
+		a:         cSum,
+		b:         cSum,
+		wantScore: 1,
+	}, {
+		name:     "renamed identifiers and changed constants",
+		language: mycode.Language_c,
+		a:        cSum,
+		b: strings.NewReplacer("sum", "total", "n,", "count,",
+			"&n", "&count", "< n", "< count", "x", "value",
+			"= 0", "= 100").Replace(cSum),
+		wantScore: 1,
+	}, {
+		name:     "reformatted with comments",
+		language: mycode.Language_c,
+		a:        cSum,
+		b: strings.NewReplacer("{\n", "{ // block\n", "    ", "\t",
+			"return 0;", "/* done */ return 0;").Replace(cSum),
+		wantScore: 1,
+	}, {
+		name:     "different",
+		language: mycode.Language_c,
+		a:        cSum,
+		b: `int gcd(int a, int b) {
+    while (b != 0) {
+        int t = a % b;
+        a = b;
+        b = t;
+    }
+    return a;
+}
+`,
+		wantMaxScore: 0.3,
+	}, {
+		name:      "shorter than k",
+		language:  mycode.Language_c,
+		a:         "x = 1;",
+		b:         "x = 1;",
+		wantScore: 0,
+	}, {
+		name:      "shorter than w k-grams",
+		language:  mycode.Language_c,
+		a:         "x = y + 1;",
+		b:         "a = b + 2;",
+		wantScore: 1,
+	}, {
+		name:      "empty",
+		language:  mycode.Language_python,
+		a:         "",
+		b:         "# comment only\n",
+		wantScore: 0,
+	}, {
+		name:     "pascal case insensitive",
+		language: mycode.Language_pascal,
+		a: `program Sum;
+var a, b: integer;
+begin
+  readln(a, b);
+  writeln(a + b);
+end.
+`,
+		b: `PROGRAM SUM;
+VAR A, B: INTEGER;
+BEGIN
+  READLN(A, B);
+  WRITELN(A + B);
+END.
+`,
+		wantScore: 1,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			score, _ := Compare(Fingerprints(tt.language, tt.a),
+				Fingerprints(tt.language, tt.b))
+
+			switch {
+			case tt.wantMaxScore != 0:
+				if score > tt.wantMaxScore {
+					t.Errorf("score = %v, want at most %v", score,
+						tt.wantMaxScore)
+				}
+			case score != tt.wantScore:
+				t.Errorf("score = %v, want %v", score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestCompareFragments(t *testing.T) {
+
+	b := "// copied\n\n" + cSum
+
+	_, fs := Compare(Fingerprints(mycode.Language_c, cSum),
+		Fingerprints(mycode.Language_c, b))
+
+	if len(fs) != 1 {
+		t.Fatalf("fragments = %+v, want one", fs)
+	}
+
+	f := fs[0]
+
+	// Preprocessor line is skipped and winnowing may skip few tokens at the
+	// ends, copy is shifted by two lines.
+	if f.AStartLine < 3 || f.AStartLine > 4 || f.AEndLine < 12 ||
+		f.AEndLine > 13 || f.BStartLine != f.AStartLine+2 ||
+		f.BEndLine != f.AEndLine+2 {
+		t.Errorf("fragment = %+v, want lines 3-13 of A and 5-15 of B", f)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		language mycode.Language
+		source   string
+		want     []token
+	}{{
+		name:     "unterminated string",
+		language: mycode.Language_c,
+		source:   "s = \"abc\nx;",
+		want: []token{{"I", 1}, {"=", 1}, {"S", 1}, {"I", 2},
+			{";", 2}},
+	}, {
+		name:     "unterminated string at end",
+		language: mycode.Language_c,
+		source:   `s = "abc\`,
+		want:     []token{{"I", 1}, {"=", 1}, {"S", 1}},
+	}, {
+		name:     "unterminated comment",
+		language: mycode.Language_cpp,
+		source:   "x;\n/* comment\ny;",
+		want:     []token{{"I", 1}, {";", 1}},
+	}, {
+		name:     "unterminated python triple quoted string",
+		language: mycode.Language_python,
+		source:   "x = '''abc\ny",
+		want:     []token{{"I", 1}, {"=", 1}, {"S", 1}},
+	}, {
+		name:     "unterminated go raw string",
+		language: mycode.Language_go,
+		source:   "x := `a\nb",
+		want:     []token{{"I", 1}, {":", 1}, {"=", 1}, {"S", 1}},
+	}, {
+		name:     "unterminated pascal comment",
+		language: mycode.Language_pascal,
+		source:   "x := 1; { comment\n(* y",
+		want: []token{{"I", 1}, {":", 1}, {"=", 1}, {"N", 1},
+			{";", 1}},
+	}, {
+		name:     "pascal keywords case folded",
+		language: mycode.Language_pascal,
+		source:   "BEGIN WriteLn('a') End.",
+		want: []token{{"begin", 1}, {"I", 1}, {"(", 1}, {"S", 1},
+			{")", 1}, {"end", 1}, {".", 1}},
+	}, {
+		name:     "c keywords case sensitive",
+		language: mycode.Language_c,
+		source:   "Return return",
+		want:     []token{{"I", 1}, {"return", 1}},
+	}, {
+		name:     "preprocessor line only at line start",
+		language: mycode.Language_c,
+		source:   "#define N 10\nx # y",
+		want:     []token{{"I", 2}, {"#", 2}, {"I", 2}},
+	}, {
+		name:     "python comment",
+		language: mycode.Language_python,
+		source:   "x # comment\n# another\ny",
+		want:     []token{{"I", 1}, {"I", 3}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenize(tt.language, tt.source)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
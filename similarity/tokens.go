@@ -0,0 +1,205 @@
+package similarity
+
+import (
+	"strings"
+
+	"github.com/dimuls/mycode"
+)
+
+const (
+	identifierToken = "I"
+	numberToken     = "N"
+	stringToken     = "S"
+)
+
+type token struct {
+	text string
+	line int
+}
+
+var keywords = map[mycode.Language]map[string]bool{
+	mycode.Language_c: set("auto break case char const continue default do " +
+		"double else enum extern float for goto if int long register " +
+		"return short signed sizeof static struct switch typedef union " +
+		"unsigned void volatile while"),
+	mycode.Language_cpp: set("auto bool break case catch char class const " +
+		"continue default delete do double else enum explicit extern " +
+		"false float for friend goto if inline int long namespace new " +
+		"operator private protected public return short signed sizeof " +
+		"static struct switch template this throw true try typedef " +
+		"typename union unsigned using virtual void volatile while"),
+	mycode.Language_go: set("break case chan const continue default defer " +
+		"else fallthrough for func go goto if import interface map " +
+		"package range return select struct switch type var"),
+	mycode.Language_java: set("abstract boolean break byte case catch char " +
+		"class continue default do double else extends false final " +
+		"finally float for if implements import instanceof int " +
+		"interface long new null package private protected public " +
+		"return short static super switch this throw throws true try " +
+		"void while"),
+	mycode.Language_pascal: set("and array begin case const div do downto " +
+		"else end for function if in mod not of or procedure program " +
+		"record repeat then to type until uses var while"),
+	mycode.Language_python: set("and as assert break class continue def " +
+		"del elif else except False finally for from global if import " +
+		"in is lambda None nonlocal not or pass raise return True try " +
+		"while with yield"),
+}
+
+func set(words string) map[string]bool {
+	s := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		s[w] = true
+	}
+	return s
+}
+
+// tokenize splits source to tokens skipping whitespaces and comments.
+// Identifiers, numbers and strings are replaced with their kinds, so
+// renaming variables or changing constants doesn't hide copied code.
+// C and C++ preprocessor lines are skipped as boilerplate.
+func tokenize(l mycode.Language, source string) []token {
+
+	var (
+		ts   []token
+		line = 1
+		s    = source
+		kws  = keywords[l]
+	)
+
+	lineComment := "//"
+	if l == mycode.Language_python {
+		lineComment = "#"
+	}
+
+	skip := func(n int) {
+		line += strings.Count(s[:n], "\n")
+		s = s[n:]
+	}
+
+	skipUntil := func(start int, end string) {
+		i := strings.Index(s[start:], end)
+		if i < 0 {
+			skip(len(s))
+			return
+		}
+		skip(start + i + len(end))
+	}
+
+	lineStart := true
+
+	for len(s) > 0 {
+		c := s[0]
+
+		switch {
+		case c == '\n':
+			lineStart = true
+			skip(1)
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r':
+			skip(1)
+			continue
+
+		case lineStart && c == '#' &&
+			(l == mycode.Language_c || l == mycode.Language_cpp):
+			skipUntil(0, "\n")
+			lineStart = true
+			continue
+
+		case strings.HasPrefix(s, lineComment):
+			skipUntil(0, "\n")
+			lineStart = true
+			continue
+
+		case strings.HasPrefix(s, "/*") && l != mycode.Language_python &&
+			l != mycode.Language_pascal:
+			skipUntil(2, "*/")
+			continue
+
+		case l == mycode.Language_pascal && c == '{':
+			skipUntil(1, "}")
+			continue
+
+		case l == mycode.Language_pascal && strings.HasPrefix(s, "(*"):
+			skipUntil(2, "*)")
+			continue
+		}
+
+		lineStart = false
+
+		switch {
+		case isLetter(c):
+			n := 1
+			for n < len(s) && (isLetter(s[n]) || isDigit(s[n])) {
+				n++
+			}
+			w := s[:n]
+			if l == mycode.Language_pascal {
+				w = strings.ToLower(w)
+			}
+			if kws[w] {
+				ts = append(ts, token{text: w, line: line})
+			} else {
+				ts = append(ts, token{text: identifierToken, line: line})
+			}
+			skip(n)
+
+		case isDigit(c):
+			n := 1
+			for n < len(s) && (isLetter(s[n]) || isDigit(s[n]) ||
+				s[n] == '.') {
+				n++
+			}
+			ts = append(ts, token{text: numberToken, line: line})
+			skip(n)
+
+		case c == '"' || c == '\'' || c == '`' && l == mycode.Language_go:
+			ts = append(ts, token{text: stringToken, line: line})
+			skip(stringLen(s, l))
+
+		default:
+			ts = append(ts, token{text: s[:1], line: line})
+			skip(1)
+		}
+	}
+
+	return ts
+}
+
+// stringLen returns length of the string literal in the beginning of s.
+// Python triple quoted strings and Go raw strings may span lines.
+func stringLen(s string, l mycode.Language) int {
+
+	if l == mycode.Language_python &&
+		(strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''")) {
+		i := strings.Index(s[3:], s[:3])
+		if i < 0 {
+			return len(s)
+		}
+		return 3 + i + 3
+	}
+
+	q := s[0]
+
+	for n := 1; n < len(s); n++ {
+		switch {
+		case s[n] == '\\' && q != '`' && l != mycode.Language_pascal:
+			n++
+		case s[n] == q:
+			return n + 1
+		case s[n] == '\n' && q != '`':
+			return n
+		}
+	}
+
+	return len(s)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}